@@ -0,0 +1,2772 @@
+/*
+Copyright 2017 IBM, Infosys Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+	"github.com/ruchika05/learn-chaincode/router"
+)
+
+// FIOrderCounterKey is the world state key holding the last FIOrderID issued
+const FIOrderCounterKey = "FIOrderCounter"
+
+// TradeObjectCounterKey is the world state key holding the last TradeObjectID issued
+const TradeObjectCounterKey = "TradeObjectCounter"
+
+// TransactionCounterKey is the world state key holding the last TransactionID issued
+const TransactionCounterKey = "TransactionCounter"
+
+// ExecutionCounterKey is the world state key holding the last ExecutionID issued
+const ExecutionCounterKey = "ExecutionCounter"
+
+// OrderBookSequenceCounterKey is the world state key holding the last BookSequence issued
+const OrderBookSequenceCounterKey = "OrderBookSequenceCounter"
+
+// first ID handed out is counterStart + 1
+const counterStart = 10000
+
+// Returns the next ID for the counter stored under counterKey.
+// The counter lives in world state rather than in process memory so that every
+// endorsing peer issues the same IDs and a chaincode restart does not reissue them.
+func generateID(stub shim.ChaincodeStubInterface, counterKey string) (string, error) {
+	var counter int
+
+	bytesRead, err := stub.GetState(counterKey)
+	if err != nil {
+		fmt.Printf("Failed to read the counter %s : %v\n", counterKey, err)
+		return "", err
+	}
+	if len(bytesRead) != 0 {
+		counter, err = strconv.Atoi(string(bytesRead))
+		if err != nil {
+			fmt.Printf("Counter %s is corrupt : %v\n", counterKey, err)
+			return "", err
+		}
+	} else {
+		counter = counterStart
+	}
+	counter = counter + 1
+	err = stub.PutState(counterKey, []byte(strconv.Itoa(counter)))
+	if err != nil {
+		fmt.Printf("Failed to update the counter %s : %v\n", counterKey, err)
+		return "", err
+	}
+	return strconv.Itoa(counter), nil
+}
+
+const (
+	millisPerSecond     = int64(time.Second / time.Millisecond)
+	nanosPerMillisecond = int64(time.Millisecond / time.Nanosecond)
+)
+
+func msToTime(ms string) (time.Time, error) {
+	msInt, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(msInt/millisPerSecond,
+		(msInt%millisPerSecond)*nanosPerMillisecond), nil
+}
+
+//FIOrder is created for trade requests received by FI
+type FIOrder struct {
+	FIOrderID       string        `json:"fiOrderID"`       // auto-generated unique ID for the FI Order
+	FIID            string        `json:"fiID"`            // Unique ID of the FI
+	ClientOrderRef  string        `json:"clientOrderRef"`  // FI's own reference for the order, optional
+	CustodianBankID string        `json:"custodianBankID"` // Unique ID of the Custodian Bank
+	BrokerID        string        `json:"brokerID"`        // Unique ID of the broker
+	AccountID       string        `json:"accountID"`       // Account ID of the FI
+	Product         string        `json:"product"`         // name of the Product
+	Status          OrderStatus   `json:"status"`          // status of the order
+	CreationDate    time.Time     `json:"creationDate"`    // date of creation of FIOrder
+	StockID         string        `json:"stockID"`         // name of the Stock
+	Quantity        int           `json:"quantity"`        // quantity of stock to be bought/sold
+	Exchange        string        `json:"exchange"`        // name of exchange
+	OrderValidity   OrderValidity `json:"orderValidity"`   // validity of the order, DAY when not given
+	ValidTill       string        `json:"validTill"`       // expiry of a GTD order in milliseconds
+	Side            OrderSide     `json:"side"`            // Buy or Sell
+	OrderType       OrderType     `json:"orderType"`       // type of Order, Market or Limit
+	LimitPrice      float32       `json:"limitPrice"`      // limit price
+
+	ExecutedPrice        float32   `json:"executedPrice"`        // average price the broker executed the order at
+	ExecutedQuantity     int       `json:"executedQuantity"`     // quantity the broker executed so far
+	ExchangeTradeNumbers []string  `json:"exchangeTradeNumbers"` // trade numbers given by the exchange, one per fill
+	RejectionReason      string    `json:"rejectionReason"`      // reason given by the broker for rejecting the order
+	TradeObjectID        string    `json:"tradeObjectID"`        // settlement trade the order is part of
+	ExpiryDate           time.Time `json:"expiryDate"`           // date the order expires on, zero for GTC orders
+	ExpiredQuantity      int       `json:"expiredQuantity"`      // quantity left unfilled when the order expired or was cancelled
+	BookSequence         string    `json:"bookSequence"`         // time priority of the order in the order book, empty when it never rested there
+
+	Amendments            []OrderAmendment       `json:"amendments"`            // earlier terms of the order, oldest first
+	AllocationInstruction *AllocationInstruction `json:"allocationInstruction"` // split over the FI's accounts, needed to settle
+}
+
+// OrderSide tells whether an order buys or sells stock
+type OrderSide string
+
+// FIOrder sides
+const (
+	OrderSideBuy  OrderSide = "Buy"
+	OrderSideSell OrderSide = "Sell"
+)
+
+// OrderType tells how an order is priced
+type OrderType string
+
+// FIOrder types
+const (
+	OrderTypeMarket OrderType = "Market" // executed at the market price, without a LimitPrice
+	OrderTypeLimit  OrderType = "Limit"  // executed at LimitPrice or better
+)
+
+// OrderStatus is the state of a FIOrder in its lifecycle
+type OrderStatus string
+
+// FIOrder statuses
+const (
+	OrderStatusNew             OrderStatus = "New"
+	OrderStatusAcknowledged    OrderStatus = "Acknowledged"
+	OrderStatusPartiallyFilled OrderStatus = "PartiallyFilled"
+	OrderStatusExecuted        OrderStatus = "Executed"
+	OrderStatusAllocated       OrderStatus = "Allocated"
+	OrderStatusSettled         OrderStatus = "Settled"
+	OrderStatusCancelled       OrderStatus = "Cancelled"
+	OrderStatusRejected        OrderStatus = "Rejected"
+	OrderStatusExpired         OrderStatus = "Expired"
+)
+
+// orderStatusTransitions ==> orderStatusTransitions[from] = []allowed next status
+// A status missing from the table is final.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew:             {OrderStatusAcknowledged, OrderStatusCancelled, OrderStatusRejected, OrderStatusExpired},
+	OrderStatusAcknowledged:    {OrderStatusPartiallyFilled, OrderStatusExecuted, OrderStatusCancelled, OrderStatusRejected, OrderStatusExpired},
+	OrderStatusPartiallyFilled: {OrderStatusPartiallyFilled, OrderStatusExecuted},
+	OrderStatusExecuted:        {OrderStatusAllocated},
+	OrderStatusAllocated:       {OrderStatusSettled},
+}
+
+// Returns the OrderStatus named status or an error if there is no such status
+func parseOrderStatus(status string) (OrderStatus, error) {
+	switch orderStatus := OrderStatus(status); orderStatus {
+	case OrderStatusNew, OrderStatusAcknowledged, OrderStatusPartiallyFilled, OrderStatusExecuted,
+		OrderStatusAllocated, OrderStatusSettled, OrderStatusCancelled, OrderStatusRejected, OrderStatusExpired:
+		return orderStatus, nil
+	}
+	return "", ccerror.New(ccerror.BadArgs, "Unknown order status "+strconv.Quote(status))
+}
+
+// Moves fiOrder to the status next, refusing transitions missing from orderStatusTransitions
+func (fiOrder *FIOrder) transitionTo(next OrderStatus) error {
+	allowed := orderStatusTransitions[fiOrder.Status]
+	for _, status := range allowed {
+		if status == next {
+			fiOrder.Status = next
+			return nil
+		}
+	}
+	fmt.Printf("Order %s can not move from %s to %s\n", fiOrder.FIOrderID, fiOrder.Status, next)
+	if len(allowed) == 0 {
+		return ccerror.Errorf(ccerror.InvalidState, "Order %s can not move from %s to %s: %s is final", fiOrder.FIOrderID, fiOrder.Status, next, fiOrder.Status)
+	}
+	return ccerror.Errorf(ccerror.InvalidState, "Order %s can not move from %s to %s: allowed next statuses are %v", fiOrder.FIOrderID, fiOrder.Status, next, allowed)
+}
+
+// OrderValidity is how long an order stays open for execution
+type OrderValidity string
+
+// FIOrder validities
+const (
+	OrderValidityDay OrderValidity = "DAY" // until the end of the (UTC) day the order was created
+	OrderValidityGTC OrderValidity = "GTC" // good till cancelled
+	OrderValidityGTD OrderValidity = "GTD" // good till the date in ValidTill
+	OrderValidityIOC OrderValidity = "IOC" // immediate or cancel: whatever the first fill leaves open expires
+)
+
+// Returns the date fiOrder expires on, the zero time for an order that never expires
+func orderExpiryDate(fiOrder FIOrder) (time.Time, error) {
+	switch fiOrder.OrderValidity {
+	case OrderValidityDay, OrderValidityIOC:
+		return fiOrder.CreationDate.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour), nil
+	case OrderValidityGTC:
+		return time.Time{}, nil
+	case OrderValidityGTD:
+		if len(fiOrder.ValidTill) == 0 {
+			return time.Time{}, ccerror.New(ccerror.BadArgs, "GTD order has no validTill date")
+		}
+		expiryDate, err := msToTime(fiOrder.ValidTill)
+		if err != nil {
+			return time.Time{}, ccerror.New(ccerror.BadArgs, "Invalid validTill date "+fiOrder.ValidTill)
+		}
+		return expiryDate.UTC(), nil
+	}
+	return time.Time{}, ccerror.New(ccerror.BadArgs, "Unknown order validity "+strconv.Quote(string(fiOrder.OrderValidity)))
+}
+
+// Returns true when the validity of fiOrder has lapsed at now
+func (fiOrder *FIOrder) isExpiredAt(now time.Time) bool {
+	return !fiOrder.ExpiryDate.IsZero() && !now.Before(fiOrder.ExpiryDate)
+}
+
+// Expires the part of fiOrder left open. Fills already confirmed stand, so a
+// partially filled order ends up Executed for the quantity filled.
+func (fiOrder *FIOrder) expire() error {
+	fiOrder.ExpiredQuantity = fiOrder.Quantity - fiOrder.ExecutedQuantity
+	if fiOrder.ExecutedQuantity > 0 {
+		return fiOrder.transitionTo(OrderStatusExecuted)
+	}
+	return fiOrder.transitionTo(OrderStatusExpired)
+}
+
+// Cancels the part of fiOrder left open. As with expire, a partially filled order
+// ends up Executed for the quantity filled.
+func (fiOrder *FIOrder) cancel() error {
+	fiOrder.ExpiredQuantity = fiOrder.Quantity - fiOrder.ExecutedQuantity
+	if fiOrder.ExecutedQuantity > 0 {
+		return fiOrder.transitionTo(OrderStatusExecuted)
+	}
+	return fiOrder.transitionTo(OrderStatusCancelled)
+}
+
+// Records a fill of quantity at price, identified by tradeNumber, moving fiOrder to
+// PartiallyFilled or to Executed once nothing is left open
+func (fiOrder *FIOrder) applyFill(price float32, quantity int, tradeNumber string) error {
+	next := OrderStatusPartiallyFilled
+	if quantity == fiOrder.Quantity-fiOrder.ExecutedQuantity {
+		next = OrderStatusExecuted
+	}
+	if err := fiOrder.transitionTo(next); err != nil {
+		return err
+	}
+	// ExecutedPrice is the average price over all the fills of the order
+	executedValue := fiOrder.ExecutedPrice*float32(fiOrder.ExecutedQuantity) + price*float32(quantity)
+	fiOrder.ExecutedQuantity = fiOrder.ExecutedQuantity + quantity
+	fiOrder.ExecutedPrice = executedValue / float32(fiOrder.ExecutedQuantity)
+	fiOrder.ExchangeTradeNumbers = append(fiOrder.ExchangeTradeNumbers, tradeNumber)
+	return nil
+}
+
+// Refuses to act on fiOrder once its validity has lapsed at the time of the current transaction
+func checkNotExpired(stub shim.ChaincodeStubInterface, fiOrder *FIOrder) error {
+	now, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	if fiOrder.isExpiredAt(now) {
+		fmt.Printf("Order %s expired on %v\n", fiOrder.FIOrderID, fiOrder.ExpiryDate)
+		return ccerror.New(ccerror.InvalidState, "Order "+fiOrder.FIOrderID+" expired on "+fiOrder.ExpiryDate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// FieldError describes one invalid field of a submitted order
+type FieldError struct {
+	Order   int    `json:"order"`   // position of the order in the submitted batch
+	Field   string `json:"field"`   // JSON name of the field
+	Message string `json:"message"` // what is wrong with the field
+}
+
+// OrderValidationError lists every invalid field found in a batch of submitted orders
+type OrderValidationError []FieldError
+
+func (e OrderValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fmt.Sprintf("order %d: %s %s", fieldError.Order, fieldError.Field, fieldError.Message)
+	}
+	return "Invalid orders: " + strings.Join(messages, "; ")
+}
+
+// Returns e as a bad-args error carrying the invalid fields as its details
+func (e OrderValidationError) ccError() *ccerror.Error {
+	return ccerror.New(ccerror.BadArgs, e.Error()).WithDetails([]FieldError(e))
+}
+
+// Returns the fields of fiOrder that are invalid for an order open at now, keyed by their JSON name
+func validateFIOrder(fiOrder FIOrder, now time.Time) []FieldError {
+	var fieldErrors []FieldError
+	invalid := func(field string, message string) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Message: message})
+	}
+
+	if len(fiOrder.FIID) == 0 {
+		invalid("fiID", "is required")
+	}
+	if len(fiOrder.BrokerID) == 0 {
+		invalid("brokerID", "is required")
+	}
+	if len(fiOrder.StockID) == 0 {
+		invalid("stockID", "is required")
+	}
+	if fiOrder.Quantity <= 0 {
+		invalid("quantity", "must be positive")
+	}
+	if fiOrder.Side != OrderSideBuy && fiOrder.Side != OrderSideSell {
+		invalid("side", "must be "+string(OrderSideBuy)+" or "+string(OrderSideSell))
+	}
+
+	switch fiOrder.OrderType {
+	case OrderTypeMarket:
+		if fiOrder.LimitPrice != 0 {
+			invalid("limitPrice", "is not allowed on "+string(OrderTypeMarket)+" orders")
+		}
+	case OrderTypeLimit:
+		if fiOrder.LimitPrice <= 0 {
+			invalid("limitPrice", "must be positive on "+string(OrderTypeLimit)+" orders")
+		}
+	default:
+		invalid("orderType", "must be "+string(OrderTypeMarket)+" or "+string(OrderTypeLimit))
+	}
+
+	switch fiOrder.OrderValidity {
+	case OrderValidityDay, OrderValidityGTC, OrderValidityIOC:
+		if len(fiOrder.ValidTill) > 0 {
+			invalid("validTill", "is only allowed on "+string(OrderValidityGTD)+" orders")
+		}
+	case OrderValidityGTD:
+		if len(fiOrder.ValidTill) == 0 {
+			invalid("validTill", "is required on "+string(OrderValidityGTD)+" orders")
+		} else if _, err := msToTime(fiOrder.ValidTill); err != nil {
+			invalid("validTill", "must be a date in milliseconds")
+		}
+	default:
+		invalid("orderValidity", "must be "+string(OrderValidityDay)+", "+string(OrderValidityGTC)+", "+
+			string(OrderValidityGTD)+" or "+string(OrderValidityIOC))
+	}
+	if expiryDate, err := orderExpiryDate(fiOrder); err == nil && !expiryDate.IsZero() && !now.Before(expiryDate) {
+		invalid("validTill", "is "+expiryDate.Format(time.RFC3339)+", which has passed")
+	}
+	return fieldErrors
+}
+
+// Returns the JSON names of the fields of a submitted fiOrder that only the chaincode sets but are not empty.
+// FIOrderID, Status and CreationDate are not listed, createOrdersByFI overwrites them.
+func serverOwnedFields(fiOrder FIOrder) []string {
+	var fields []string
+	set := func(field string, isSet bool) {
+		if isSet {
+			fields = append(fields, field)
+		}
+	}
+
+	set("executedPrice", fiOrder.ExecutedPrice != 0)
+	set("executedQuantity", fiOrder.ExecutedQuantity != 0)
+	set("exchangeTradeNumbers", len(fiOrder.ExchangeTradeNumbers) > 0)
+	set("rejectionReason", len(fiOrder.RejectionReason) > 0)
+	set("tradeObjectID", len(fiOrder.TradeObjectID) > 0)
+	set("expiryDate", !fiOrder.ExpiryDate.IsZero())
+	set("expiredQuantity", fiOrder.ExpiredQuantity != 0)
+	set("bookSequence", len(fiOrder.BookSequence) > 0)
+	set("amendments", len(fiOrder.Amendments) > 0)
+	set("allocationInstruction", fiOrder.AllocationInstruction != nil)
+	return fields
+}
+
+// Validates a batch of orders submitted at now, returning a bad-args error whose details list every invalid field
+func validateFIOrders(fiOrders []FIOrder, now time.Time) error {
+	var validationError OrderValidationError
+	clientOrderRefs := make(map[string]bool)
+
+	for i, fiOrder := range fiOrders {
+		if len(fiOrder.ClientOrderRef) > 0 {
+			if clientOrderRefs[fiOrder.ClientOrderRef] {
+				validationError = append(validationError, FieldError{Order: i, Field: "clientOrderRef", Message: "is used by another order of the batch"})
+			}
+			clientOrderRefs[fiOrder.ClientOrderRef] = true
+		}
+		if fiOrder.Status != "" && fiOrder.Status != OrderStatusNew {
+			validationError = append(validationError, FieldError{Order: i, Field: "status", Message: "must be empty or " + string(OrderStatusNew)})
+		}
+		for _, field := range serverOwnedFields(fiOrder) {
+			validationError = append(validationError, FieldError{Order: i, Field: field, Message: "is set by the chaincode and must be empty"})
+		}
+		if len(fiOrder.OrderValidity) == 0 {
+			fiOrder.OrderValidity = OrderValidityDay
+		}
+		fiOrder.CreationDate = now
+		for _, fieldError := range validateFIOrder(fiOrder, now) {
+			fieldError.Order = i
+			validationError = append(validationError, fieldError)
+		}
+	}
+	if len(validationError) > 0 {
+		fmt.Printf("%v\n", validationError)
+		return validationError.ccError()
+	}
+	return nil
+}
+
+// TradeObject Details
+type TradeObject struct {
+	TradeObjectID    string      `json:"tradeObjectID"`    // auto-generated unique ID for the Trade TradeObject
+	SettlementStatus TradeStatus `json:"settlementStatus"` // status of the settlement
+	OderTradeNumber  string      `json:"oderTradeNumber"`  // OderTradeNumber
+	SettlementDate   time.Time   `json:"settlementDate"`   // date of settlement
+	CustodianBankID  string      `json:"custodianBankID"`  // Unique ID of the Custodian Bank settling the trade
+	FIOrderIDs       []string    `json:"fiOrderIDs"`       // orders settled by the trade
+}
+
+// Transaction details
+type Transaction struct {
+	TransactionID    string    `json:"transactionID"` // auto-generated unique ID for the Transaction
+	FIID             string    `json:"fiID"`          // Unique ID of the FI
+	AccountID        string    `json:"accountID"`     // account id of the FI
+	StockID          string    `json:"stockID"`       // id of the stock
+	Quanity          int       `json:"quantity"`      // quantity of stocks traded
+	TransactionDate  time.Time `json:"txnDate"`       // date of Transaction
+	TransactionType  string    `json:"txnType"`       // type of txn - debit/credit
+	EffectiveBalance int       `json:"balance"`       // effective balance of stocks post transaction
+	FIOrderID        string    `json:"fiOrderID"`     // settled order the transaction books
+	TradeObjectID    string    `json:"tradeObjectID"` // settlement trade of the order
+}
+
+// Transaction types
+const (
+	TransactionTypeDebit  = "Debit"
+	TransactionTypeCredit = "Credit"
+)
+
+// Every FIOrder, TradeObject and Transaction is stored under its own composite key
+// ==> objectType\x00ID\x00 so that writing one object never rewrites another.
+const (
+	FIOrderObjectType     = "FIOrder"
+	TradeObjectObjectType = "TradeObject"
+	TransactionObjectType = "Transaction"
+	HoldingObjectType     = "Holding" // Holding\x00FIID\x00StockID\x00AccountID\x00
+)
+
+// Secondary indexes over the FIOrders. Each index entry is a composite key whose
+// last attribute is the FIOrderID, so a partial key range-scans every order under it.
+const (
+	OrdersByFIIndex        = "fi~status~fiOrderID"
+	OrdersByBrokerIndex    = "broker~status~fiOrderID"
+	OrdersByCustodianIndex = "custodian~status~fiOrderID"
+	OrdersByStatusIndex    = "status~fiOrderID"
+	OrdersByStockIndex     = "stock~fiOrderID"
+)
+
+// Secondary indexes over the FIOrders of a party, ordered by creation date.
+// searchOrders pages through them from its bookmark.
+const (
+	OrdersByFIAndDateIndex        = "fi~creationDate~fiOrderID"
+	OrdersByBrokerAndDateIndex    = "broker~creationDate~fiOrderID"
+	OrdersByCustodianAndDateIndex = "custodian~creationDate~fiOrderID"
+)
+
+// Secondary indexes over the TradeObjects, ending with the TradeObjectID
+const (
+	TradesByCustodianIndex = "custodian~status~tradeObjectID"
+	TradesByStatusIndex    = "status~tradeObjectID"
+)
+
+// AllFIOrdersKey is the key of the map used to hold every FIOrder before orders were
+// stored under their own keys ==> AllFIOrders[FIOrderID] = FIOrder
+const AllFIOrdersKey = "AllFIOrders"
+
+// keys of the order index maps superseded by the composite key indexes
+var legacyIndexKeys = []string{"AllOrdersForFI", "AllOrdersForBroker", "AllTradeObjects"}
+
+const (
+	compositeKeySeparator = "\x00"
+	maxUnicodeRune        = "\U0010FFFF"
+)
+
+// value stored under index keys; the key itself carries all the information
+var indexValue = []byte{0x00}
+
+// ConfirmedToFIOrder ==> ConfirmedToFIOrder[ExchangeTradeNumber] = FIOrderID
+// Each entry is stored under its own composite key holding the FIOrderID.
+const ConfirmedToFIOrderObjectType = "ConfirmedToFIOrder"
+
+// OrderBookIndex holds the limit orders resting in the order book of an exchange and stock.
+// Entries sort in price-time priority: by price, best first, then by BookSequence.
+const OrderBookIndex = "book~exchange~stock~side~price~sequence~fiOrderID"
+
+// ExecutionObjectType is the object type of the Executions of the order books
+const ExecutionObjectType = "Execution"
+
+// ExecutionsByOrderIndex holds an entry for each of the two orders of an Execution
+const ExecutionsByOrderIndex = "fiOrder~executionID"
+
+// Order book prices are kept with 4 decimals, and a Buy price is stored as maxPriceTicks - price
+const (
+	priceTicksPerUnit = 10000
+	maxPriceTicks     = int64(999999999999999999)
+)
+
+// Secondary indexes over the Transactions of a FI, ordered by transaction date.
+// They take the place of ListOfTransactionsForFI[FIID]=(ListOfStocks[StockID]=[]TransactionID).
+const (
+	TransactionsByFIIndex         = "fi~txnDate~transactionID"
+	TransactionsByFIAndStockIndex = "fi~stock~txnDate~transactionID"
+)
+
+// layout of dates in index keys, fixed width so that keys sort by date
+const indexDateLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Formats date for use as an index attribute
+func formatIndexDate(date time.Time) string {
+	return date.UTC().Format(indexDateLayout)
+}
+
+// CapitalMarketChainCode defined the chaincode for global mobile wallet
+type CapitalMarketChainCode struct {
+}
+
+// Builds the composite key objectType\x00attr1\x00attr2\x00...
+func createCompositeKey(objectType string, attributes ...string) (string, error) {
+	key := objectType + compositeKeySeparator
+	for _, attribute := range attributes {
+		if strings.Contains(attribute, compositeKeySeparator) {
+			return "", ccerror.New(ccerror.BadArgs, "Attribute "+strconv.Quote(attribute)+" contains the composite key separator")
+		}
+		key = key + attribute + compositeKeySeparator
+	}
+	return key, nil
+}
+
+// Splits a key built by createCompositeKey back into its object type and attributes
+func splitCompositeKey(compositeKey string) (string, []string) {
+	components := strings.Split(strings.TrimSuffix(compositeKey, compositeKeySeparator), compositeKeySeparator)
+	return components[0], components[1:]
+}
+
+// Returns the keys of every composite key starting with objectType and the given attributes
+func getKeysByPartialCompositeKey(stub shim.ChaincodeStubInterface, objectType string, attributes ...string) ([]string, error) {
+	startKey, err := createCompositeKey(objectType, attributes...)
+	if err != nil {
+		return nil, err
+	}
+	return getKeysInRange(stub, startKey, startKey+maxUnicodeRune)
+}
+
+// Returns the keys between startKey and endKey
+func getKeysInRange(stub shim.ChaincodeStubInterface, startKey string, endKey string) ([]string, error) {
+	var keys []string
+
+	iter, err := stub.RangeQueryState(startKey, endKey)
+	if err != nil {
+		fmt.Printf("Failed to range query %q : %v\n", startKey, err)
+		return nil, err
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			fmt.Printf("Failed to range query %q : %v\n", startKey, err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Reads the JSON stored under key into value.
+// value is left untouched when nothing is stored under key.
+func getStateJSON(stub shim.ChaincodeStubInterface, key string, value interface{}) error {
+	bytesRead, err := stub.GetState(key)
+	if err != nil {
+		fmt.Printf("Failed to read %s from block chain :%v\n", key, err)
+		return err
+	}
+	if len(bytesRead) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(bytesRead, value)
+	if err != nil {
+		fmt.Printf("Failed to unmarshal %s :%v\n", key, err)
+		return err
+	}
+	return nil
+}
+
+// Writes value to the world state under key as JSON
+func putStateJSON(stub shim.ChaincodeStubInterface, key string, value interface{}) error {
+	bytesArray, err := json.Marshal(value)
+	if err != nil {
+		fmt.Printf("Failed to marshal %s :%v\n", key, err)
+		return err
+	}
+	err = stub.PutState(key, bytesArray)
+	if err != nil {
+		fmt.Printf("Failed to write %s to block chain :%v\n", key, err)
+		return err
+	}
+	return nil
+}
+
+// Builds the composite keys of index entries given as {index, attributes...}
+func buildIndexKeys(indexes [][]string) ([]string, error) {
+	var keys []string
+	for _, index := range indexes {
+		key, err := createCompositeKey(index[0], index[1:]...)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Deletes the index entries in oldIndexKeys that are not in newIndexKeys and writes newIndexKeys
+func updateIndexEntries(stub shim.ChaincodeStubInterface, oldIndexKeys []string, newIndexKeys []string) error {
+	kept := make(map[string]bool)
+	for _, newKey := range newIndexKeys {
+		kept[newKey] = true
+	}
+	for _, oldKey := range oldIndexKeys {
+		if kept[oldKey] {
+			continue
+		}
+		if err := stub.DelState(oldKey); err != nil {
+			fmt.Printf("Failed to delete index entry %q : %v\n", oldKey, err)
+			return err
+		}
+	}
+	for _, indexKey := range newIndexKeys {
+		if err := stub.PutState(indexKey, indexValue); err != nil {
+			fmt.Printf("Failed to write index entry %q : %v\n", indexKey, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the index keys fiOrder must be reachable from
+func orderIndexKeys(fiOrder FIOrder) ([]string, error) {
+	creationDate := formatIndexDate(fiOrder.CreationDate)
+	indexes := [][]string{
+		{OrdersByFIIndex, fiOrder.FIID, string(fiOrder.Status), fiOrder.FIOrderID},
+		{OrdersByBrokerIndex, fiOrder.BrokerID, string(fiOrder.Status), fiOrder.FIOrderID},
+		{OrdersByCustodianIndex, fiOrder.CustodianBankID, string(fiOrder.Status), fiOrder.FIOrderID},
+		{OrdersByStatusIndex, string(fiOrder.Status), fiOrder.FIOrderID},
+		{OrdersByStockIndex, fiOrder.StockID, fiOrder.FIOrderID},
+		{OrdersByFIAndDateIndex, fiOrder.FIID, creationDate, fiOrder.FIOrderID},
+		{OrdersByBrokerAndDateIndex, fiOrder.BrokerID, creationDate, fiOrder.FIOrderID},
+		{OrdersByCustodianAndDateIndex, fiOrder.CustodianBankID, creationDate, fiOrder.FIOrderID},
+	}
+	if fiOrder.isInBook() {
+		indexes = append(indexes, []string{OrderBookIndex, fiOrder.Exchange, fiOrder.StockID, string(fiOrder.Side),
+			bookPriceKey(fiOrder.Side, fiOrder.LimitPrice), fiOrder.BookSequence, fiOrder.FIOrderID})
+	}
+	return buildIndexKeys(indexes)
+}
+
+// Returns the FIOrder stored under fiOrderID, nil if there is none
+func getFIOrder(stub shim.ChaincodeStubInterface, fiOrderID string) (*FIOrder, error) {
+	var fiOrder *FIOrder
+
+	key, err := createCompositeKey(FIOrderObjectType, fiOrderID)
+	if err != nil {
+		return nil, err
+	}
+	err = getStateJSON(stub, key, &fiOrder)
+	if err != nil {
+		return nil, err
+	}
+	return fiOrder, nil
+}
+
+// Writes fiOrder under its own key and brings its index entries up to date.
+// previous is the order as stored before this change, nil for a new order.
+func putFIOrder(stub shim.ChaincodeStubInterface, fiOrder FIOrder, previous *FIOrder) error {
+	key, err := createCompositeKey(FIOrderObjectType, fiOrder.FIOrderID)
+	if err != nil {
+		return err
+	}
+	newIndexKeys, err := orderIndexKeys(fiOrder)
+	if err != nil {
+		return err
+	}
+	var oldIndexKeys []string
+	if previous != nil {
+		oldIndexKeys, err = orderIndexKeys(*previous)
+		if err != nil {
+			return err
+		}
+	}
+	if err = putStateJSON(stub, key, fiOrder); err != nil {
+		return err
+	}
+	return updateIndexEntries(stub, oldIndexKeys, newIndexKeys)
+}
+
+// Returns the FIOrders found by range-scanning index with the leading attributes given
+func getOrdersByIndex(stub shim.ChaincodeStubInterface, index string, attributes ...string) ([]FIOrder, error) {
+	var fiOrders []FIOrder
+
+	keys, err := getKeysByPartialCompositeKey(stub, index, attributes...)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		_, indexAttributes := splitCompositeKey(key)
+		fiOrderID := indexAttributes[len(indexAttributes)-1]
+		fiOrder, err := getFIOrder(stub, fiOrderID)
+		if err != nil {
+			return nil, err
+		}
+		if fiOrder == nil {
+			fmt.Printf("Index %s refers to missing order %s\n", index, fiOrderID)
+			continue
+		}
+		fiOrders = append(fiOrders, *fiOrder)
+	}
+	return fiOrders, nil
+}
+
+// Returns the TradeObject stored under tradeObjectID, nil if there is none
+func getTradeObject(stub shim.ChaincodeStubInterface, tradeObjectID string) (*TradeObject, error) {
+	var tradeObject *TradeObject
+
+	key, err := createCompositeKey(TradeObjectObjectType, tradeObjectID)
+	if err != nil {
+		return nil, err
+	}
+	err = getStateJSON(stub, key, &tradeObject)
+	if err != nil {
+		return nil, err
+	}
+	return tradeObject, nil
+}
+
+// Returns the index keys tradeObject must be reachable from
+func tradeIndexKeys(tradeObject TradeObject) ([]string, error) {
+	return buildIndexKeys([][]string{
+		{TradesByCustodianIndex, tradeObject.CustodianBankID, string(tradeObject.SettlementStatus), tradeObject.TradeObjectID},
+		{TradesByStatusIndex, string(tradeObject.SettlementStatus), tradeObject.TradeObjectID},
+	})
+}
+
+// Writes tradeObject under its own key and brings its index entries up to date.
+// previous is the trade as stored before this change, nil for a new trade.
+func putTradeObject(stub shim.ChaincodeStubInterface, tradeObject TradeObject, previous *TradeObject) error {
+	key, err := createCompositeKey(TradeObjectObjectType, tradeObject.TradeObjectID)
+	if err != nil {
+		return err
+	}
+	newIndexKeys, err := tradeIndexKeys(tradeObject)
+	if err != nil {
+		return err
+	}
+	var oldIndexKeys []string
+	if previous != nil {
+		oldIndexKeys, err = tradeIndexKeys(*previous)
+		if err != nil {
+			return err
+		}
+	}
+	if err = putStateJSON(stub, key, tradeObject); err != nil {
+		return err
+	}
+	return updateIndexEntries(stub, oldIndexKeys, newIndexKeys)
+}
+
+// Returns the Transaction stored under transactionID, nil if there is none
+func getTransaction(stub shim.ChaincodeStubInterface, transactionID string) (*Transaction, error) {
+	var transaction *Transaction
+
+	key, err := createCompositeKey(TransactionObjectType, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	err = getStateJSON(stub, key, &transaction)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Writes transaction under its own key along with its index entries.
+// Transactions are never changed once written.
+func putTransaction(stub shim.ChaincodeStubInterface, transaction Transaction) error {
+	key, err := createCompositeKey(TransactionObjectType, transaction.TransactionID)
+	if err != nil {
+		return err
+	}
+	txnDate := formatIndexDate(transaction.TransactionDate)
+	indexKeys, err := buildIndexKeys([][]string{
+		{TransactionsByFIIndex, transaction.FIID, txnDate, transaction.TransactionID},
+		{TransactionsByFIAndStockIndex, transaction.FIID, transaction.StockID, txnDate, transaction.TransactionID},
+	})
+	if err != nil {
+		return err
+	}
+	if err = putStateJSON(stub, key, transaction); err != nil {
+		return err
+	}
+	return updateIndexEntries(stub, nil, indexKeys)
+}
+
+// Moves the orders of the old single AllFIOrders map to their own keys and drops
+// the old index maps, so a chaincode upgraded in place keeps its order book.
+func migrateAllFIOrders(stub shim.ChaincodeStubInterface) error {
+	allFIOrders := make(map[string]FIOrder)
+
+	err := getStateJSON(stub, AllFIOrdersKey, &allFIOrders)
+	if err != nil {
+		return err
+	}
+	for fiOrderID, fiOrder := range allFIOrders {
+		fiOrder.FIOrderID = fiOrderID
+		if err = putFIOrder(stub, fiOrder, nil); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Migrated %d orders from %s\n", len(allFIOrders), AllFIOrdersKey)
+	for _, key := range append(legacyIndexKeys, AllFIOrdersKey) {
+		if err = stub.DelState(key); err != nil {
+			fmt.Printf("Failed to delete %s : %v\n", key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Init function
+func (t *CapitalMarketChainCode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	err := migrateAllFIOrders(stub)
+	if err != nil {
+		fmt.Printf("Failed to migrate the orders : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+	fmt.Println("Initialization complete")
+
+	return nil, nil
+}
+
+// Role is the kind of party a caller acts as
+type Role string
+
+// Roles of the parties to an order
+const (
+	RoleFI        Role = "FI"
+	RoleBroker    Role = "Broker"
+	RoleCustodian Role = "Custodian"
+)
+
+// RoleAttribute is the enrollment certificate attribute carrying the Role of the caller
+const RoleAttribute = "role"
+
+// roleIDAttributes ==> roleIDAttributes[Role] = certificate attribute carrying the ID of the party the caller acts for
+var roleIDAttributes = map[Role]string{
+	RoleFI:        "fiID",
+	RoleBroker:    "brokerID",
+	RoleCustodian: "custodianBankID",
+}
+
+// Checks that the caller's enrollment certificate names it as a role acting for partyID
+func authorize(stub shim.ChaincodeStubInterface, role Role, partyID string) error {
+	if len(partyID) == 0 {
+		return ccerror.New(ccerror.BadArgs, "No "+string(role)+" given to act for")
+	}
+	ok, err := stub.VerifyAttribute(RoleAttribute, []byte(role))
+	if err != nil || !ok {
+		fmt.Printf("Caller is not a %s : %v\n", role, err)
+		return ccerror.New(ccerror.Unauthorized, "Caller is not enrolled as "+string(role))
+	}
+	ok, err = stub.VerifyAttribute(roleIDAttributes[role], []byte(partyID))
+	if err != nil || !ok {
+		fmt.Printf("Caller does not act for %s %s : %v\n", role, partyID, err)
+		return ccerror.New(ccerror.Unauthorized, "Caller is not allowed to act for "+string(role)+" "+partyID)
+	}
+	return nil
+}
+
+// Returns the ID of the party the caller acts for as role
+func getCallerPartyID(stub shim.ChaincodeStubInterface, role Role) (string, error) {
+	ok, err := stub.VerifyAttribute(RoleAttribute, []byte(role))
+	if err != nil || !ok {
+		fmt.Printf("Caller is not a %s : %v\n", role, err)
+		return "", ccerror.New(ccerror.Unauthorized, "Caller is not enrolled as "+string(role))
+	}
+	partyID, err := stub.ReadCertAttribute(roleIDAttributes[role])
+	if err != nil || len(partyID) == 0 {
+		fmt.Printf("Unable to read the %s attribute of the caller : %v\n", roleIDAttributes[role], err)
+		return "", ccerror.New(ccerror.Unauthorized, "Caller certificate has no "+roleIDAttributes[role]+" attribute")
+	}
+	return string(partyID), nil
+}
+
+// Checks that the caller is enrolled as a FI, a broker or a custodian bank
+func authorizeAnyRole(stub shim.ChaincodeStubInterface) error {
+	for _, role := range []Role{RoleFI, RoleBroker, RoleCustodian} {
+		if _, err := getCallerPartyID(stub, role); err == nil {
+			return nil
+		}
+	}
+	return ccerror.New(ccerror.Unauthorized, "Caller is not enrolled as a FI, broker or custodian bank")
+}
+
+// EventType is the name of the chaincode event set by a transaction changing orders or trades
+type EventType string
+
+// Chaincode events
+const (
+	EventOrdersCreated      EventType = "OrdersCreated"      // createOrdersByFI
+	EventOrderStatusChanged EventType = "OrderStatusChanged" // acknowledgeOrder, confirmOrder, rejectOrder, cancelOrder, expireOrders
+	EventTradeCreated       EventType = "TradeCreated"       // createSettlementTrade, the orders becoming Allocated
+	EventTradeSettled       EventType = "TradeSettled"       // settleTrade, the orders becoming Settled
+	EventOrdersMatched      EventType = "OrdersMatched"      // submitOrderToBook, the order submitted first then the orders it traded with
+)
+
+// OrderEvent describes an order created or changed by a transaction
+type OrderEvent struct {
+	FIOrderID       string      `json:"fiOrderID"`
+	FIID            string      `json:"fiID"`
+	BrokerID        string      `json:"brokerID"`
+	CustodianBankID string      `json:"custodianBankID"`
+	StockID         string      `json:"stockID"`
+	PreviousStatus  OrderStatus `json:"previousStatus"` // empty for a new order
+	Status          OrderStatus `json:"status"`
+}
+
+// LifecycleEvent is the JSON payload of the chaincode events, named after their Type.
+// Fabric delivers a single event per transaction, so a transaction changing several orders
+// sets one event listing all of them, for instance:
+//
+//	{"type":"OrderStatusChanged","timestamp":"2017-07-14T02:40:00Z",
+//	 "orders":[{"fiOrderID":"10001","fiID":"FI1","brokerID":"B1","custodianBankID":"C1",
+//	            "stockID":"IBM","previousStatus":"New","status":"Acknowledged"}]}
+//
+// Trade is only set by TradeCreated and TradeSettled, Executions by OrdersMatched.
+type LifecycleEvent struct {
+	Type       EventType    `json:"type"`
+	Timestamp  time.Time    `json:"timestamp"` // time of the transaction
+	Orders     []OrderEvent `json:"orders"`
+	Trade      *TradeObject `json:"trade,omitempty"`
+	Executions []Execution  `json:"executions,omitempty"`
+}
+
+// Returns the OrderEvent of fiOrder, previous being the order before the transaction and nil for a new order
+func newOrderEvent(fiOrder FIOrder, previous *FIOrder) OrderEvent {
+	orderEvent := OrderEvent{
+		FIOrderID:       fiOrder.FIOrderID,
+		FIID:            fiOrder.FIID,
+		BrokerID:        fiOrder.BrokerID,
+		CustodianBankID: fiOrder.CustodianBankID,
+		StockID:         fiOrder.StockID,
+		Status:          fiOrder.Status,
+	}
+	if previous != nil {
+		orderEvent.PreviousStatus = previous.Status
+	}
+	return orderEvent
+}
+
+// Sets the chaincode event of the transaction
+func setLifecycleEvent(stub shim.ChaincodeStubInterface, eventType EventType, orders []OrderEvent, tradeObject *TradeObject) error {
+	return setEvent(stub, &LifecycleEvent{Type: eventType, Orders: orders, Trade: tradeObject})
+}
+
+// Sets event, stamped with the time of the transaction, as the chaincode event of the transaction
+func setEvent(stub shim.ChaincodeStubInterface, event *LifecycleEvent) error {
+	var err error
+
+	event.Timestamp, err = getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		fmt.Printf("Error marshalling %s event : %v\n", event.Type, err)
+		return err
+	}
+	err = stub.SetEvent(string(event.Type), payload)
+	if err != nil {
+		fmt.Printf("Error setting %s event : %v\n", event.Type, err)
+		return ccerror.New(ccerror.Internal, "Failed to set the "+string(event.Type)+" event")
+	}
+	return nil
+}
+
+// OrderResult reports the ID and status given to one order of a batch submitted by createOrdersByFI
+type OrderResult struct {
+	ClientOrderRef string      `json:"clientOrderRef"` // reference the FI submitted the order with
+	FIOrderID      string      `json:"fiOrderID"`      // ID of the order on the ledger
+	Status         OrderStatus `json:"status"`         // status of the order once created
+}
+
+// add orders created by the FI ==> args: FIID of the caller, []FIOrder JSON
+// The caller must be enrolled as the FI FIID and every order must be for FIID; orders without
+// a fiID are taken to be for it. Either every order of the batch is created or none is. Returns an OrderResult per order,
+// in the order they were submitted.
+func (t *CapitalMarketChainCode) createOrdersByFI(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Creating all orders by FI")
+	fmt.Printf("args[0]: %v\n", args[0])
+	fmt.Printf("args[1]: %v\n", args[1])
+
+	var fiOrders []FIOrder
+	var err error
+
+	err = json.Unmarshal([]byte(args[1]), &fiOrders)
+	if err != nil {
+		fmt.Printf("Error unmarshalling fi orders data : %v\n", err)
+		return nil, ccerror.New(ccerror.BadArgs, "Failed to create fi orders")
+	}
+	fmt.Printf("fi orders after unmarshal: %v\n", fiOrders)
+
+	if len(fiOrders) == 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "There are no orders available for the FI")
+	}
+	FIID := args[0]
+	if err = authorize(stub, RoleFI, FIID); err != nil {
+		return nil, err
+	}
+	for i := range fiOrders {
+		if len(fiOrders[i].FIID) == 0 {
+			fiOrders[i].FIID = FIID
+		} else if fiOrders[i].FIID != FIID {
+			fmt.Printf("Order %d is for FI %s, submitted by %s\n", i, fiOrders[i].FIID, FIID)
+			return nil, ccerror.New(ccerror.Unauthorized, "Order "+strconv.Itoa(i)+" is for FI "+fiOrders[i].FIID+", not the submitting FI "+FIID)
+		}
+	}
+	creationDate, err := getTxTime(stub)
+	if err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to create fi orders")
+	}
+
+	if err = validateFIOrders(fiOrders, creationDate); err != nil {
+		return nil, err
+	}
+
+	// every order is checked and given its ID before any is written, so that a
+	// failure leaves the whole batch unwritten
+	results := make([]OrderResult, len(fiOrders))
+	for i := range fiOrders {
+		fiOrder := &fiOrders[i]
+		fiOrder.Status = OrderStatusNew
+		fiOrder.CreationDate = creationDate
+		if len(fiOrder.OrderValidity) == 0 {
+			fiOrder.OrderValidity = OrderValidityDay
+		}
+		fiOrder.ExpiryDate, err = orderExpiryDate(*fiOrder)
+		if err != nil {
+			return nil, err
+		}
+		fiOrder.FIOrderID, err = generateID(stub, FIOrderCounterKey)
+		if err != nil {
+			fmt.Printf("Error generating id for fi order : %v\n", err)
+			return nil, ccerror.New(ccerror.Internal, "Failed to create fi orders")
+		}
+		existing, err := getFIOrder(stub, fiOrder.FIOrderID)
+		if err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to create fi orders")
+		}
+		if existing != nil {
+			fmt.Printf("FIOrderID %s already exists\n", fiOrder.FIOrderID)
+			return nil, ccerror.New(ccerror.Conflict, "FIOrderID "+fiOrder.FIOrderID+" already exists")
+		}
+		results[i] = OrderResult{ClientOrderRef: fiOrder.ClientOrderRef, FIOrderID: fiOrder.FIOrderID, Status: fiOrder.Status}
+	}
+	orderEvents := make([]OrderEvent, len(fiOrders))
+	for i, fiOrder := range fiOrders {
+		if err = putFIOrder(stub, fiOrder, nil); err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to create fi orders")
+		}
+		orderEvents[i] = newOrderEvent(fiOrder, nil)
+	}
+	if err = setLifecycleEvent(stub, EventOrdersCreated, orderEvents, nil); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Orders created successfully : %v\n", results)
+	return json.Marshal(&results)
+}
+
+// returned by the queries for a party that has no records of the kind queried at all,
+// as opposed to records of which none match the query
+var errNoRecords = errors.New("no records")
+
+// Returns errNoRecords when there is no entry for partyID in index
+func checkPartyHasRecords(stub shim.ChaincodeStubInterface, index string, partyID string) error {
+	keys, err := getKeysByPartialCompositeKey(stub, index, partyID)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errNoRecords
+	}
+	return nil
+}
+
+// Returns the orders of the party partyID in index, restricted to Status when it is not empty.
+// errNoRecords is returned when the party has no orders at all.
+func getAllOrdersForPartyBasedOnStatus(stub shim.ChaincodeStubInterface, index string, partyID string, Status string) ([]FIOrder, error) {
+	attributes := []string{partyID}
+	if len(Status) > 0 {
+		orderStatus, err := parseOrderStatus(Status)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, string(orderStatus))
+	}
+	fiOrdersByStatus, err := getOrdersByIndex(stub, index, attributes...)
+	if err != nil {
+		return nil, err
+	}
+	if len(fiOrdersByStatus) == 0 {
+		if err = checkPartyHasRecords(stub, index, partyID); err != nil {
+			return nil, err
+		}
+	}
+	return fiOrdersByStatus, nil
+}
+
+/*
+	Returns the list of FIOrders for a FI based on status
+*/
+func getAllOrdersForFIBasedOnStatus(FIID string, Status string, stub shim.ChaincodeStubInterface) ([]FIOrder, error) {
+	if err := authorize(stub, RoleFI, FIID); err != nil {
+		return nil, err
+	}
+	fiOrdersByStatus, err := getAllOrdersForPartyBasedOnStatus(stub, OrdersByFIIndex, FIID, Status)
+	if err == errNoRecords {
+		fmt.Printf("Unable to find any orders for FI %s\n", FIID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("List Of Orders by FI %s : %v \n", FIID, fiOrdersByStatus)
+	return fiOrdersByStatus, nil
+}
+
+/*
+	Returns the list of FIOrders held with a Custodian Bank based on status
+*/
+func getAllOrdersForCustodianBasedOnStatus(CustodianBankID string, Status string, stub shim.ChaincodeStubInterface) ([]FIOrder, error) {
+	if err := authorize(stub, RoleCustodian, CustodianBankID); err != nil {
+		return nil, err
+	}
+	fiOrdersByStatus, err := getAllOrdersForPartyBasedOnStatus(stub, OrdersByCustodianIndex, CustodianBankID, Status)
+	if err == errNoRecords {
+		fmt.Printf("Unable to find any orders for Custodian %s\n", CustodianBankID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("List Of Orders by Custodian %s : %v \n", CustodianBankID, fiOrdersByStatus)
+	return fiOrdersByStatus, nil
+}
+
+/*
+	Returns the list of FIOrders for a Broker
+*/
+func getAllOrdersForBrokerBasedOnStatus(BrokerID string, Status string, stub shim.ChaincodeStubInterface) ([]FIOrder, error) {
+	if err := authorize(stub, RoleBroker, BrokerID); err != nil {
+		return nil, err
+	}
+	fiOrdersByStatus, err := getAllOrdersForPartyBasedOnStatus(stub, OrdersByBrokerIndex, BrokerID, Status)
+	if err == errNoRecords {
+		fmt.Printf("Unable to find any orders for Broker %s\n", BrokerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("List Of Orders by FI %s : %v \n", BrokerID, fiOrdersByStatus)
+	return fiOrdersByStatus, nil
+}
+
+// Sort orders and page sizes of searchOrders
+const (
+	SortAscending        = "asc"
+	SortDescending       = "desc"
+	DefaultOrderPageSize = 50
+	MaxOrderPageSize     = 500
+)
+
+// OrderSearchFilter selects the orders returned by searchOrders.
+// At least one of FIID, BrokerID and CustodianBankID must be given, and the caller must act for one of them.
+// Empty fields match every order.
+type OrderSearchFilter struct {
+	FIID            string        `json:"fiID"`
+	BrokerID        string        `json:"brokerID"`
+	CustodianBankID string        `json:"custodianBankID"`
+	StockID         string        `json:"stockID"`
+	Exchange        string        `json:"exchange"`
+	OrderType       OrderType     `json:"orderType"`
+	Statuses        []OrderStatus `json:"statuses"`  // orders in any of these statuses
+	FromDate        string        `json:"fromDate"`  // earliest creation date in milliseconds, inclusive
+	ToDate          string        `json:"toDate"`    // latest creation date in milliseconds, inclusive
+	SortOrder       string        `json:"sortOrder"` // asc (default) or desc on creationDate, then fiOrderID
+	PageSize        int           `json:"pageSize"`  // DefaultOrderPageSize when 0, at most MaxOrderPageSize
+	Bookmark        string        `json:"bookmark"`  // bookmark of the previous page, empty for the first page
+}
+
+// OrderSearchResult is one page of orders found by searchOrders
+type OrderSearchResult struct {
+	Orders   []FIOrder `json:"orders"`
+	Bookmark string    `json:"bookmark"` // pass in the filter to get the next page, empty on the last page
+}
+
+// Position of an order in the results of searchOrders; the creation date of an order never changes
+// so the position is stable across pages.
+func orderSortKey(fiOrder FIOrder) string {
+	return formatIndexDate(fiOrder.CreationDate) + compositeKeySeparator + fiOrder.FIOrderID
+}
+
+// Returns the creation date index and the party to scan for filter, after checking that the caller
+// acts for one of the parties it names
+func orderSearchScope(stub shim.ChaincodeStubInterface, filter OrderSearchFilter) (string, string, error) {
+	parties := []struct {
+		role    Role
+		partyID string
+		index   string
+	}{
+		{RoleFI, filter.FIID, OrdersByFIAndDateIndex},
+		{RoleBroker, filter.BrokerID, OrdersByBrokerAndDateIndex},
+		{RoleCustodian, filter.CustodianBankID, OrdersByCustodianAndDateIndex},
+	}
+	var err error = ccerror.New(ccerror.BadArgs, "searchOrders needs a fiID, brokerID or custodianBankID")
+	for _, party := range parties {
+		if len(party.partyID) == 0 {
+			continue
+		}
+		if err = authorize(stub, party.role, party.partyID); err != nil {
+			continue
+		}
+		return party.index, party.partyID, nil
+	}
+	return "", "", err
+}
+
+// Checks fiOrder against the fields of filter not covered by the index scanned
+func matchesOrderSearchFilter(fiOrder FIOrder, filter OrderSearchFilter, from time.Time, to time.Time) bool {
+	if (len(filter.FIID) > 0 && fiOrder.FIID != filter.FIID) ||
+		(len(filter.BrokerID) > 0 && fiOrder.BrokerID != filter.BrokerID) ||
+		(len(filter.CustodianBankID) > 0 && fiOrder.CustodianBankID != filter.CustodianBankID) ||
+		(len(filter.StockID) > 0 && fiOrder.StockID != filter.StockID) ||
+		(len(filter.Exchange) > 0 && fiOrder.Exchange != filter.Exchange) ||
+		(len(filter.OrderType) > 0 && fiOrder.OrderType != filter.OrderType) {
+		return false
+	}
+	if len(filter.Statuses) > 0 {
+		found := false
+		for _, status := range filter.Statuses {
+			if fiOrder.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !from.IsZero() && fiOrder.CreationDate.Before(from) {
+		return false
+	}
+	if !to.IsZero() && fiOrder.CreationDate.After(to) {
+		return false
+	}
+	return true
+}
+
+/*
+	Returns a page of the orders matching an OrderSearchFilter
+*/
+func searchOrders(filter OrderSearchFilter, stub shim.ChaincodeStubInterface) (OrderSearchResult, error) {
+	var result OrderSearchResult
+	var from, to time.Time
+	var err error
+
+	for i, status := range filter.Statuses {
+		filter.Statuses[i], err = parseOrderStatus(string(status))
+		if err != nil {
+			return result, err
+		}
+	}
+	if len(filter.FromDate) > 0 {
+		if from, err = msToTime(filter.FromDate); err != nil {
+			return result, ccerror.New(ccerror.BadArgs, "Invalid from date "+filter.FromDate)
+		}
+	}
+	if len(filter.ToDate) > 0 {
+		if to, err = msToTime(filter.ToDate); err != nil {
+			return result, ccerror.New(ccerror.BadArgs, "Invalid to date "+filter.ToDate)
+		}
+	}
+	if len(filter.SortOrder) == 0 {
+		filter.SortOrder = SortAscending
+	}
+	if filter.SortOrder != SortAscending && filter.SortOrder != SortDescending {
+		return result, ccerror.New(ccerror.BadArgs, "Unknown sort order "+filter.SortOrder+", expecting "+SortAscending+" or "+SortDescending)
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = DefaultOrderPageSize
+	}
+	if filter.PageSize < 0 || filter.PageSize > MaxOrderPageSize {
+		return result, ccerror.Errorf(ccerror.BadArgs, "Page size must be between 1 and %d", MaxOrderPageSize)
+	}
+	var after string
+	if len(filter.Bookmark) > 0 {
+		decoded, err := base64.URLEncoding.DecodeString(filter.Bookmark)
+		if err != nil {
+			return result, ccerror.New(ccerror.BadArgs, "Invalid bookmark "+filter.Bookmark)
+		}
+		after = string(decoded)
+	}
+
+	index, partyID, err := orderSearchScope(stub, filter)
+	if err != nil {
+		return result, err
+	}
+
+	// Only the keys between the dates of the filter and the bookmark are scanned, and orders
+	// are read one at a time until the page is full.
+	prefix, err := createCompositeKey(index, partyID)
+	if err != nil {
+		return result, err
+	}
+	startKey, endKey := prefix, prefix+maxUnicodeRune
+	if !from.IsZero() {
+		startKey = prefix + formatIndexDate(from)
+	}
+	if !to.IsZero() {
+		endKey = prefix + formatIndexDate(to) + compositeKeySeparator + maxUnicodeRune
+	}
+	if len(after) > 0 {
+		if filter.SortOrder == SortAscending && prefix+after > startKey {
+			startKey = prefix + after
+		}
+		if filter.SortOrder == SortDescending && prefix+after+compositeKeySeparator < endKey {
+			endKey = prefix + after + compositeKeySeparator
+		}
+	}
+	keys, err := getKeysInRange(stub, startKey, endKey)
+	if err != nil {
+		return result, err
+	}
+	if filter.SortOrder == SortAscending {
+		sort.Strings(keys)
+	} else {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+
+	result.Orders = []FIOrder{}
+	for _, key := range keys {
+		_, attributes := splitCompositeKey(key)
+		sortKey := attributes[1] + compositeKeySeparator + attributes[2]
+		if len(after) > 0 && ((filter.SortOrder == SortAscending && sortKey <= after) ||
+			(filter.SortOrder == SortDescending && sortKey >= after)) {
+			continue
+		}
+		fiOrder, err := getFIOrder(stub, attributes[2])
+		if err != nil {
+			return result, err
+		}
+		if fiOrder == nil {
+			fmt.Printf("Index %s refers to missing order %s\n", index, attributes[2])
+			continue
+		}
+		if !matchesOrderSearchFilter(*fiOrder, filter, from, to) {
+			continue
+		}
+		if len(result.Orders) == filter.PageSize {
+			result.Bookmark = base64.URLEncoding.EncodeToString([]byte(orderSortKey(result.Orders[len(result.Orders)-1])))
+			break
+		}
+		result.Orders = append(result.Orders, *fiOrder)
+	}
+	if len(result.Orders) == 0 {
+		if err = checkPartyHasRecords(stub, index, partyID); err != nil {
+			return result, err
+		}
+	}
+	fmt.Printf("Search found %d orders, bookmark %q\n", len(result.Orders), result.Bookmark)
+	return result, nil
+}
+
+// OrderConfirmation is sent by the broker once an order has been executed on the exchange
+type OrderConfirmation struct {
+	ExecutedPrice       float32 `json:"executedPrice"`       // price the order was executed at
+	ExecutedQuantity    int     `json:"executedQuantity"`    // quantity of stock executed
+	ExchangeTradeNumber string  `json:"exchangeTradeNumber"` // trade number given by the exchange
+}
+
+// Returns the order fiOrderID after checking that brokerID is the broker it was routed to
+func getOrderForBroker(stub shim.ChaincodeStubInterface, brokerID string, fiOrderID string) (*FIOrder, error) {
+	if err := authorize(stub, RoleBroker, brokerID); err != nil {
+		return nil, err
+	}
+	fiOrder, err := getFIOrder(stub, fiOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if fiOrder == nil {
+		fmt.Printf("Order %s not found\n", fiOrderID)
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+fiOrderID)
+	}
+	if fiOrder.BrokerID != brokerID {
+		fmt.Printf("Order %s is not routed to broker %s\n", fiOrderID, brokerID)
+		return nil, ccerror.New(ccerror.Unauthorized, "Broker "+brokerID+" is not allowed to act on order "+fiOrderID)
+	}
+	if err = checkNotExpired(stub, fiOrder); err != nil {
+		return nil, err
+	}
+	return fiOrder, nil
+}
+
+// Stores the status change of fiOrder and returns the updated order
+func updateOrderByBroker(stub shim.ChaincodeStubInterface, fiOrder FIOrder, previous *FIOrder) ([]byte, error) {
+	err := putFIOrder(stub, fiOrder, previous)
+	if err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to update order "+fiOrder.FIOrderID)
+	}
+	err = setLifecycleEvent(stub, EventOrderStatusChanged, []OrderEvent{newOrderEvent(fiOrder, previous)}, nil)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Order %s is %s\n", fiOrder.FIOrderID, fiOrder.Status)
+	return json.Marshal(&fiOrder)
+}
+
+// acknowledge the receipt of an order by the broker ==> args: brokerID, fiOrderID
+func (t *CapitalMarketChainCode) acknowledgeOrder(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	previous, err := getOrderForBroker(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	fiOrder := *previous
+	if err = fiOrder.transitionTo(OrderStatusAcknowledged); err != nil {
+		return nil, err
+	}
+	return updateOrderByBroker(stub, fiOrder, previous)
+}
+
+// confirm the execution of an order by the broker ==> args: brokerID, fiOrderID, OrderConfirmation JSON
+func (t *CapitalMarketChainCode) confirmOrder(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var confirmation OrderConfirmation
+
+	err := json.Unmarshal([]byte(args[2]), &confirmation)
+	if err != nil {
+		fmt.Printf("Error unmarshalling confirmation : %v\n", err)
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid confirmation for order "+args[1])
+	}
+	previous, err := getOrderForBroker(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(confirmation.ExchangeTradeNumber) == 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "Confirmation for order "+args[1]+" has no exchangeTradeNumber")
+	}
+	if confirmation.ExecutedPrice <= 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "Confirmation for order "+args[1]+" has no executedPrice")
+	}
+	openQuantity := previous.Quantity - previous.ExecutedQuantity
+	if confirmation.ExecutedQuantity <= 0 || confirmation.ExecutedQuantity > openQuantity {
+		return nil, ccerror.New(ccerror.BadArgs, "Confirmation for order "+args[1]+" has an executedQuantity outside 1.."+strconv.Itoa(openQuantity))
+	}
+
+	confirmedKey, err := createCompositeKey(ConfirmedToFIOrderObjectType, confirmation.ExchangeTradeNumber)
+	if err != nil {
+		return nil, err
+	}
+	confirmedOrderID, err := stub.GetState(confirmedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(confirmedOrderID) != 0 {
+		fmt.Printf("Exchange trade number %s already confirms order %s\n", confirmation.ExchangeTradeNumber, confirmedOrderID)
+		return nil, ccerror.New(ccerror.Conflict, "Exchange trade number "+confirmation.ExchangeTradeNumber+" is already confirmed")
+	}
+	err = stub.PutState(confirmedKey, []byte(previous.FIOrderID))
+	if err != nil {
+		fmt.Printf("Failed to record exchange trade number %s : %v\n", confirmation.ExchangeTradeNumber, err)
+		return nil, err
+	}
+
+	fiOrder := *previous
+	err = fiOrder.applyFill(confirmation.ExecutedPrice, confirmation.ExecutedQuantity, confirmation.ExchangeTradeNumber)
+	if err != nil {
+		return nil, err
+	}
+	if fiOrder.OrderValidity == OrderValidityIOC && fiOrder.Status == OrderStatusPartiallyFilled {
+		if err = fiOrder.expire(); err != nil {
+			return nil, err
+		}
+	}
+	return updateOrderByBroker(stub, fiOrder, previous)
+}
+
+// reject an order by the broker ==> args: brokerID, fiOrderID, reason
+func (t *CapitalMarketChainCode) rejectOrder(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	previous, err := getOrderForBroker(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	fiOrder := *previous
+	if err = fiOrder.transitionTo(OrderStatusRejected); err != nil {
+		return nil, err
+	}
+	fiOrder.RejectionReason = args[2]
+	return updateOrderByBroker(stub, fiOrder, previous)
+}
+
+// TradeStatus is the settlement state of a TradeObject
+type TradeStatus string
+
+// TradeObject settlement statuses
+const (
+	TradeStatusPending TradeStatus = "Pending"
+	TradeStatusSettled TradeStatus = "Settled"
+)
+
+// SettlementTradeRequest is sent by the custodian bank to group executed orders into a trade
+type SettlementTradeRequest struct {
+	OderTradeNumber string   `json:"oderTradeNumber"` // custodian's reference for the trade
+	FIOrderIDs      []string `json:"fiOrderIDs"`      // executed orders to settle together
+}
+
+// Returns the trade tradeObjectID after checking that custodianBankID is the custodian it belongs to
+func getTradeForCustodian(stub shim.ChaincodeStubInterface, custodianBankID string, tradeObjectID string) (*TradeObject, error) {
+	if err := authorize(stub, RoleCustodian, custodianBankID); err != nil {
+		return nil, err
+	}
+	tradeObject, err := getTradeObject(stub, tradeObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if tradeObject == nil {
+		fmt.Printf("Trade %s not found\n", tradeObjectID)
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find trade "+tradeObjectID)
+	}
+	if tradeObject.CustodianBankID != custodianBankID {
+		fmt.Printf("Trade %s does not belong to custodian %s\n", tradeObjectID, custodianBankID)
+		return nil, ccerror.New(ccerror.Unauthorized, "Custodian "+custodianBankID+" is not allowed to act on trade "+tradeObjectID)
+	}
+	return tradeObject, nil
+}
+
+// create a settlement trade grouping executed orders ==> args: custodianBankID, SettlementTradeRequest JSON
+func (t *CapitalMarketChainCode) createSettlementTrade(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var request SettlementTradeRequest
+	var orderEvents []OrderEvent
+
+	custodianBankID := args[0]
+	if err := authorize(stub, RoleCustodian, custodianBankID); err != nil {
+		return nil, err
+	}
+	err := json.Unmarshal([]byte(args[1]), &request)
+	if err != nil {
+		fmt.Printf("Error unmarshalling settlement trade : %v\n", err)
+		return nil, ccerror.New(ccerror.BadArgs, "Failed to create settlement trade")
+	}
+	if len(request.FIOrderIDs) == 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "A settlement trade needs at least one order")
+	}
+
+	tradeObject := TradeObject{
+		SettlementStatus: TradeStatusPending,
+		OderTradeNumber:  request.OderTradeNumber,
+		CustodianBankID:  custodianBankID,
+	}
+	tradeObject.TradeObjectID, err = generateID(stub, TradeObjectCounterKey)
+	if err != nil {
+		fmt.Printf("Error generating id for trade : %v\n", err)
+		return nil, ccerror.New(ccerror.Internal, "Failed to create settlement trade")
+	}
+
+	for _, fiOrderID := range request.FIOrderIDs {
+		previous, err := getFIOrder(stub, fiOrderID)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil {
+			return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+fiOrderID)
+		}
+		if previous.CustodianBankID != custodianBankID {
+			return nil, ccerror.New(ccerror.Unauthorized, "Order "+fiOrderID+" is not held with custodian "+custodianBankID)
+		}
+		if previous.AllocationInstruction == nil || previous.AllocationInstruction.Status != AllocationStatusAffirmed {
+			return nil, ccerror.New(ccerror.InvalidState, "Order "+fiOrderID+" has no affirmed allocation instruction")
+		}
+		if err = checkAllocatedQuantity(*previous); err != nil {
+			return nil, err
+		}
+		fiOrder := *previous
+		if err = fiOrder.transitionTo(OrderStatusAllocated); err != nil {
+			return nil, err
+		}
+		fiOrder.TradeObjectID = tradeObject.TradeObjectID
+		if err = putFIOrder(stub, fiOrder, previous); err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to update order "+fiOrderID)
+		}
+		tradeObject.FIOrderIDs = append(tradeObject.FIOrderIDs, fiOrderID)
+		orderEvents = append(orderEvents, newOrderEvent(fiOrder, previous))
+	}
+
+	if err = putTradeObject(stub, tradeObject, nil); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to create settlement trade")
+	}
+	if err = setLifecycleEvent(stub, EventTradeCreated, orderEvents, &tradeObject); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Settlement trade %s created for orders %v\n", tradeObject.TradeObjectID, tradeObject.FIOrderIDs)
+	return json.Marshal(&tradeObject)
+}
+
+// settle a pending trade and all its orders ==> args: custodianBankID, tradeObjectID, settlement date in milliseconds
+func (t *CapitalMarketChainCode) settleTrade(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var orderEvents []OrderEvent
+
+	settlementDate, err := msToTime(args[2])
+	if err != nil {
+		fmt.Printf("Invalid settlement date %s : %v\n", args[2], err)
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid settlement date "+args[2])
+	}
+	previous, err := getTradeForCustodian(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	if previous.SettlementStatus != TradeStatusPending {
+		return nil, ccerror.New(ccerror.InvalidState, "Trade "+args[1]+" is already "+string(previous.SettlementStatus))
+	}
+
+	for _, fiOrderID := range previous.FIOrderIDs {
+		previousOrder, err := getFIOrder(stub, fiOrderID)
+		if err != nil {
+			return nil, err
+		}
+		if previousOrder == nil {
+			return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+fiOrderID+" of trade "+args[1])
+		}
+		fiOrder := *previousOrder
+		if err = fiOrder.transitionTo(OrderStatusSettled); err != nil {
+			return nil, err
+		}
+		if err = putFIOrder(stub, fiOrder, previousOrder); err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to update order "+fiOrderID)
+		}
+		if err = recordSettlementTransactions(stub, fiOrder, settlementDate); err != nil {
+			fmt.Printf("Failed to book the settlement of order %s : %v\n", fiOrderID, err)
+			return nil, ccerror.Wrap(err)
+		}
+		orderEvents = append(orderEvents, newOrderEvent(fiOrder, previousOrder))
+	}
+
+	tradeObject := *previous
+	tradeObject.SettlementStatus = TradeStatusSettled
+	tradeObject.SettlementDate = settlementDate
+	if err = putTradeObject(stub, tradeObject, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to settle trade "+args[1])
+	}
+	if err = setLifecycleEvent(stub, EventTradeSettled, orderEvents, &tradeObject); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Trade %s settled on %v\n", tradeObject.TradeObjectID, settlementDate)
+	return json.Marshal(&tradeObject)
+}
+
+// Execution is a match between a buy and a sell order of the order book of an exchange and stock
+type Execution struct {
+	ExecutionID   string    `json:"executionID"`   // auto-generated unique ID, recorded as a trade number on both orders
+	Exchange      string    `json:"exchange"`      // exchange of the order book
+	StockID       string    `json:"stockID"`       // stock of the order book
+	Price         float32   `json:"price"`         // limit price of the order that was resting in the book
+	Quantity      int       `json:"quantity"`      // quantity of stock exchanged
+	BuyFIOrderID  string    `json:"buyFIOrderID"`  // buy order of the match
+	SellFIOrderID string    `json:"sellFIOrderID"` // sell order of the match
+	ExecutionDate time.Time `json:"executionDate"` // time of the transaction matching the orders
+}
+
+// BookEntry is an order resting in an order book
+type BookEntry struct {
+	Side         OrderSide `json:"side"`
+	FIOrderID    string    `json:"fiOrderID"`
+	LimitPrice   float32   `json:"limitPrice"`
+	OpenQuantity int       `json:"openQuantity"` // quantity not yet executed
+}
+
+// OrderBookResult is returned by submitOrderToBook
+type OrderBookResult struct {
+	Order      FIOrder     `json:"order"`      // the order submitted, once matched
+	Executions []Execution `json:"executions"` // matches made, in the order they were made
+}
+
+// Tells whether fiOrder rests in the order book of its exchange and stock
+func (fiOrder FIOrder) isInBook() bool {
+	return len(fiOrder.BookSequence) > 0 &&
+		(fiOrder.Status == OrderStatusAcknowledged || fiOrder.Status == OrderStatusPartiallyFilled)
+}
+
+// Returns the price attribute of an order book entry. Prices are stored as a fixed number of
+// decimals padded with zeros so that keys sort by price; Buy prices are inverted so that the
+// best bid comes first.
+func bookPriceKey(side OrderSide, price float32) string {
+	ticks := int64(float64(price)*priceTicksPerUnit + 0.5)
+	if side == OrderSideBuy {
+		ticks = maxPriceTicks - ticks
+	}
+	return fmt.Sprintf("%018d", ticks)
+}
+
+// Returns the next time priority of an order entering a book, padded so that keys sort by it
+func nextBookSequence(stub shim.ChaincodeStubInterface) (string, error) {
+	sequence, err := generateID(stub, OrderBookSequenceCounterKey)
+	if err != nil {
+		return "", err
+	}
+	counter, err := strconv.Atoi(sequence)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%018d", counter), nil
+}
+
+// Tells whether the incoming order can trade with the resting order at the resting order's price
+func crosses(incoming FIOrder, resting FIOrder) bool {
+	if incoming.OrderType == OrderTypeMarket {
+		return true
+	}
+	if incoming.Side == OrderSideBuy {
+		return resting.LimitPrice <= incoming.LimitPrice
+	}
+	return resting.LimitPrice >= incoming.LimitPrice
+}
+
+// Tells whether fiOrder can trade with the best order of the other side of its book that has not expired at now
+func crossesBook(stub shim.ChaincodeStubInterface, fiOrder FIOrder, now time.Time) (bool, error) {
+	otherSide := OrderSideSell
+	if fiOrder.Side == OrderSideSell {
+		otherSide = OrderSideBuy
+	}
+	keys, err := getKeysByPartialCompositeKey(stub, OrderBookIndex, fiOrder.Exchange, fiOrder.StockID, string(otherSide))
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		_, indexAttributes := splitCompositeKey(key)
+		resting, err := getFIOrder(stub, indexAttributes[len(indexAttributes)-1])
+		if err != nil {
+			return false, err
+		}
+		if resting == nil || resting.isExpiredAt(now) {
+			continue
+		}
+		return crosses(fiOrder, *resting), nil
+	}
+	return false, nil
+}
+
+// Returns the Execution stored under executionID, nil if there is none
+func getExecution(stub shim.ChaincodeStubInterface, executionID string) (*Execution, error) {
+	var execution *Execution
+
+	key, err := createCompositeKey(ExecutionObjectType, executionID)
+	if err != nil {
+		return nil, err
+	}
+	err = getStateJSON(stub, key, &execution)
+	if err != nil {
+		return nil, err
+	}
+	return execution, nil
+}
+
+// Writes a new execution under its own key and indexes it under both of its orders
+func putExecution(stub shim.ChaincodeStubInterface, execution Execution) error {
+	key, err := createCompositeKey(ExecutionObjectType, execution.ExecutionID)
+	if err != nil {
+		return err
+	}
+	indexKeys, err := buildIndexKeys([][]string{
+		{ExecutionsByOrderIndex, execution.BuyFIOrderID, execution.ExecutionID},
+		{ExecutionsByOrderIndex, execution.SellFIOrderID, execution.ExecutionID},
+	})
+	if err != nil {
+		return err
+	}
+	if err = putStateJSON(stub, key, execution); err != nil {
+		return err
+	}
+	return updateIndexEntries(stub, nil, indexKeys)
+}
+
+// match an acknowledged order against the order book of its exchange and stock ==> args: brokerID, fiOrderID
+// The order trades with the resting orders of the other side in price-time priority, at their limit
+// price, for as long as their prices cross. What is left of a limit order then rests in the book,
+// while what is left of a market or IOC order expires. Submitting orders to the book is optional:
+// orders executed elsewhere are reported with confirmOrder instead.
+func (t *CapitalMarketChainCode) submitOrderToBook(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var executions []Execution
+	var orderEvents []OrderEvent
+
+	previous, err := getOrderForBroker(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	if previous.Status != OrderStatusAcknowledged {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" is "+string(previous.Status)+", only "+string(OrderStatusAcknowledged)+" orders can be submitted to the order book")
+	}
+	if len(previous.BookSequence) > 0 {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" is already in the order book")
+	}
+	if len(previous.Exchange) == 0 || len(previous.StockID) == 0 {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" needs an exchange and a stockID to be submitted to the order book")
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	incoming := *previous
+	otherSide := OrderSideSell
+	if incoming.Side == OrderSideSell {
+		otherSide = OrderSideBuy
+	}
+	keys, err := getKeysByPartialCompositeKey(stub, OrderBookIndex, incoming.Exchange, incoming.StockID, string(otherSide))
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		openQuantity := incoming.Quantity - incoming.ExecutedQuantity
+		if openQuantity == 0 {
+			break
+		}
+		_, indexAttributes := splitCompositeKey(key)
+		restingID := indexAttributes[len(indexAttributes)-1]
+		restingPrevious, err := getFIOrder(stub, restingID)
+		if err != nil {
+			return nil, err
+		}
+		if restingPrevious == nil {
+			fmt.Printf("Index %s refers to missing order %s\n", OrderBookIndex, restingID)
+			continue
+		}
+		resting := *restingPrevious
+		if resting.isExpiredAt(now) {
+			// expireOrders has not run since the order lapsed; it leaves the book without trading
+			if err = resting.expire(); err != nil {
+				return nil, err
+			}
+			if err = putFIOrder(stub, resting, restingPrevious); err != nil {
+				return nil, ccerror.New(ccerror.Internal, "Failed to expire order "+restingID)
+			}
+			orderEvents = append(orderEvents, newOrderEvent(resting, restingPrevious))
+			continue
+		}
+		if !crosses(incoming, resting) {
+			break
+		}
+
+		execution := Execution{
+			Exchange:      incoming.Exchange,
+			StockID:       incoming.StockID,
+			Price:         resting.LimitPrice,
+			Quantity:      resting.Quantity - resting.ExecutedQuantity,
+			BuyFIOrderID:  incoming.FIOrderID,
+			SellFIOrderID: resting.FIOrderID,
+			ExecutionDate: now,
+		}
+		if openQuantity < execution.Quantity {
+			execution.Quantity = openQuantity
+		}
+		if incoming.Side == OrderSideSell {
+			execution.BuyFIOrderID, execution.SellFIOrderID = resting.FIOrderID, incoming.FIOrderID
+		}
+		execution.ExecutionID, err = generateID(stub, ExecutionCounterKey)
+		if err != nil {
+			fmt.Printf("Error generating id for execution : %v\n", err)
+			return nil, ccerror.New(ccerror.Internal, "Failed to match order "+args[1])
+		}
+		if err = resting.applyFill(execution.Price, execution.Quantity, execution.ExecutionID); err != nil {
+			return nil, err
+		}
+		if err = incoming.applyFill(execution.Price, execution.Quantity, execution.ExecutionID); err != nil {
+			return nil, err
+		}
+		if err = putFIOrder(stub, resting, restingPrevious); err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to update order "+restingID)
+		}
+		if err = putExecution(stub, execution); err != nil {
+			return nil, ccerror.New(ccerror.Internal, "Failed to record execution "+execution.ExecutionID)
+		}
+		executions = append(executions, execution)
+		orderEvents = append(orderEvents, newOrderEvent(resting, restingPrevious))
+		fmt.Printf("Execution %s : %d %s at %v, buy %s sell %s\n", execution.ExecutionID, execution.Quantity,
+			execution.StockID, execution.Price, execution.BuyFIOrderID, execution.SellFIOrderID)
+	}
+
+	if incoming.Quantity > incoming.ExecutedQuantity {
+		if incoming.OrderType == OrderTypeLimit && incoming.OrderValidity != OrderValidityIOC {
+			incoming.BookSequence, err = nextBookSequence(stub)
+			if err != nil {
+				fmt.Printf("Error generating book sequence : %v\n", err)
+				return nil, ccerror.New(ccerror.Internal, "Failed to book order "+args[1])
+			}
+		} else if err = incoming.expire(); err != nil {
+			return nil, err
+		}
+	}
+	if err = putFIOrder(stub, incoming, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to update order "+args[1])
+	}
+	orderEvents = append([]OrderEvent{newOrderEvent(incoming, previous)}, orderEvents...)
+	err = setEvent(stub, &LifecycleEvent{Type: EventOrdersMatched, Orders: orderEvents, Executions: executions})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Order %s is %s after %d executions\n", incoming.FIOrderID, incoming.Status, len(executions))
+	return json.Marshal(&OrderBookResult{Order: incoming, Executions: executions})
+}
+
+/*
+	Returns the orders resting in the order book of an exchange and stock, bids then asks, each
+	in price-time priority
+*/
+func getOrderBook(Exchange string, StockID string, stub shim.ChaincodeStubInterface) ([]BookEntry, error) {
+	var bookEntries []BookEntry
+
+	if _, err := getCallerPartyID(stub, RoleBroker); err != nil {
+		return nil, err
+	}
+	for _, side := range []OrderSide{OrderSideBuy, OrderSideSell} {
+		fiOrders, err := getOrdersByIndex(stub, OrderBookIndex, Exchange, StockID, string(side))
+		if err != nil {
+			return nil, err
+		}
+		for _, fiOrder := range fiOrders {
+			bookEntries = append(bookEntries, BookEntry{
+				Side:         fiOrder.Side,
+				FIOrderID:    fiOrder.FIOrderID,
+				LimitPrice:   fiOrder.LimitPrice,
+				OpenQuantity: fiOrder.Quantity - fiOrder.ExecutedQuantity,
+			})
+		}
+	}
+	return bookEntries, nil
+}
+
+/*
+	Returns the executions of an order matched in an order book, for the FI, broker or
+	custodian bank of the order
+*/
+func getExecutionsForOrder(FIOrderID string, stub shim.ChaincodeStubInterface) ([]Execution, error) {
+	var executions []Execution
+
+	fiOrder, err := getFIOrder(stub, FIOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if fiOrder == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+FIOrderID)
+	}
+	err = authorize(stub, RoleFI, fiOrder.FIID)
+	if err != nil {
+		err = authorize(stub, RoleBroker, fiOrder.BrokerID)
+	}
+	if err != nil {
+		err = authorize(stub, RoleCustodian, fiOrder.CustodianBankID)
+	}
+	if err != nil {
+		return nil, ccerror.New(ccerror.Unauthorized, "Caller is not allowed to see the executions of order "+FIOrderID)
+	}
+
+	keys, err := getKeysByPartialCompositeKey(stub, ExecutionsByOrderIndex, FIOrderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		_, indexAttributes := splitCompositeKey(key)
+		executionID := indexAttributes[len(indexAttributes)-1]
+		execution, err := getExecution(stub, executionID)
+		if err != nil {
+			return nil, err
+		}
+		if execution == nil {
+			fmt.Printf("Index %s refers to missing execution %s\n", ExecutionsByOrderIndex, executionID)
+			continue
+		}
+		executions = append(executions, *execution)
+	}
+	return executions, nil
+}
+
+// Returns the trades found by range-scanning index with the leading attributes given
+func getTradesByIndex(stub shim.ChaincodeStubInterface, index string, attributes ...string) ([]TradeObject, error) {
+	var tradeObjects []TradeObject
+
+	keys, err := getKeysByPartialCompositeKey(stub, index, attributes...)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		_, indexAttributes := splitCompositeKey(key)
+		tradeObjectID := indexAttributes[len(indexAttributes)-1]
+		tradeObject, err := getTradeObject(stub, tradeObjectID)
+		if err != nil {
+			return nil, err
+		}
+		if tradeObject == nil {
+			fmt.Printf("Index %s refers to missing trade %s\n", index, tradeObjectID)
+			continue
+		}
+		tradeObjects = append(tradeObjects, *tradeObject)
+	}
+	return tradeObjects, nil
+}
+
+// Returns the index attributes for an optional trade status, after checking it is a known one
+func tradeStatusAttributes(Status string) ([]string, error) {
+	if len(Status) == 0 {
+		return nil, nil
+	}
+	if TradeStatus(Status) != TradeStatusPending && TradeStatus(Status) != TradeStatusSettled {
+		return nil, ccerror.New(ccerror.BadArgs, "Unknown trade status "+strconv.Quote(Status))
+	}
+	return []string{Status}, nil
+}
+
+/*
+	Returns the list of TradeObjects of the calling Custodian Bank based on status
+*/
+func getAllTradesBasedOnStatus(Status string, stub shim.ChaincodeStubInterface) ([]TradeObject, error) {
+	custodianBankID, err := getCallerPartyID(stub, RoleCustodian)
+	if err != nil {
+		return nil, err
+	}
+	return getAllTradesForCustodianBasedOnStatus(custodianBankID, Status, stub)
+}
+
+/*
+	Returns the list of TradeObjects for a Custodian Bank based on status
+*/
+func getAllTradesForCustodianBasedOnStatus(CustodianBankID string, Status string, stub shim.ChaincodeStubInterface) ([]TradeObject, error) {
+	if err := authorize(stub, RoleCustodian, CustodianBankID); err != nil {
+		return nil, err
+	}
+	attributes, err := tradeStatusAttributes(Status)
+	if err != nil {
+		return nil, err
+	}
+	tradeObjects, err := getTradesByIndex(stub, TradesByCustodianIndex, append([]string{CustodianBankID}, attributes...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tradeObjects) == 0 {
+		if err = checkPartyHasRecords(stub, TradesByCustodianIndex, CustodianBankID); err != nil {
+			return nil, err
+		}
+	}
+	return tradeObjects, nil
+}
+
+// Holding is the balance of a stock held in an account of a FI
+type Holding struct {
+	FIID      string `json:"fiID"`      // Unique ID of the FI
+	AccountID string `json:"accountID"` // Account ID of the FI
+	StockID   string `json:"stockID"`   // id of the stock
+	Balance   int    `json:"balance"`   // quantity of the stock held
+}
+
+// Returns the Holding of FIID in accountID for stockID, with a zero balance if there is none yet
+func getHolding(stub shim.ChaincodeStubInterface, FIID string, accountID string, stockID string) (Holding, error) {
+	holding := Holding{FIID: FIID, AccountID: accountID, StockID: stockID}
+
+	key, err := createCompositeKey(HoldingObjectType, FIID, stockID, accountID)
+	if err != nil {
+		return holding, err
+	}
+	err = getStateJSON(stub, key, &holding)
+	return holding, err
+}
+
+// Writes holding under its FI, stock and account
+func putHolding(stub shim.ChaincodeStubInterface, holding Holding) error {
+	key, err := createCompositeKey(HoldingObjectType, holding.FIID, holding.StockID, holding.AccountID)
+	if err != nil {
+		return err
+	}
+	return putStateJSON(stub, key, holding)
+}
+
+// Records the debits or credits of a settled order against the holdings of its FI in each
+// account of its allocation instruction. Buy orders credit the accounts with the allocated
+// quantity, sell orders debit them. A debit is not refused when it takes the balance below
+// zero, as positions held before the ledger was started are not known to the chaincode.
+func recordSettlementTransactions(stub shim.ChaincodeStubInterface, fiOrder FIOrder, settlementDate time.Time) error {
+	if err := checkAllocatedQuantity(fiOrder); err != nil {
+		return err
+	}
+	for _, allocation := range fiOrder.AllocationInstruction.Allocations {
+		err := recordSettlementTransaction(stub, fiOrder, allocation, settlementDate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records the debit or credit of one allocation of a settled order and updates the holding of its account
+func recordSettlementTransaction(stub shim.ChaincodeStubInterface, fiOrder FIOrder, allocation Allocation, settlementDate time.Time) error {
+	holding, err := getHolding(stub, fiOrder.FIID, allocation.AccountID, fiOrder.StockID)
+	if err != nil {
+		return err
+	}
+	transaction := Transaction{
+		FIID:            fiOrder.FIID,
+		AccountID:       allocation.AccountID,
+		StockID:         fiOrder.StockID,
+		Quanity:         allocation.Quantity,
+		TransactionDate: settlementDate,
+		FIOrderID:       fiOrder.FIOrderID,
+		TradeObjectID:   fiOrder.TradeObjectID,
+	}
+	if fiOrder.Side == OrderSideSell {
+		transaction.TransactionType = TransactionTypeDebit
+		holding.Balance = holding.Balance - allocation.Quantity
+	} else {
+		transaction.TransactionType = TransactionTypeCredit
+		holding.Balance = holding.Balance + allocation.Quantity
+	}
+	transaction.EffectiveBalance = holding.Balance
+
+	transaction.TransactionID, err = generateID(stub, TransactionCounterKey)
+	if err != nil {
+		fmt.Printf("Error generating id for transaction : %v\n", err)
+		return err
+	}
+	if err = putTransaction(stub, transaction); err != nil {
+		return err
+	}
+	if err = putHolding(stub, holding); err != nil {
+		return err
+	}
+	fmt.Printf("Transaction %s : %s of %d %s for account %s, balance %d\n", transaction.TransactionID, transaction.TransactionType,
+		transaction.Quanity, transaction.StockID, transaction.AccountID, transaction.EffectiveBalance)
+	return nil
+}
+
+/*
+	Returns the holdings of a FI, for one stock when StockID is not empty
+*/
+func getHoldingsForFI(FIID string, StockID string, stub shim.ChaincodeStubInterface) ([]Holding, error) {
+	var holdings []Holding
+
+	if err := authorize(stub, RoleFI, FIID); err != nil {
+		return nil, err
+	}
+
+	attributes := []string{FIID}
+	if len(StockID) > 0 {
+		attributes = append(attributes, StockID)
+	}
+	keys, err := getKeysByPartialCompositeKey(stub, HoldingObjectType, attributes...)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		var holding Holding
+		if err = getStateJSON(stub, key, &holding); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, holding)
+	}
+	if len(holdings) == 0 {
+		if err = checkPartyHasRecords(stub, HoldingObjectType, FIID); err != nil {
+			return nil, err
+		}
+	}
+	return holdings, nil
+}
+
+/*
+	Returns the transactions of a FI settled between fromDate and toDate (inclusive,
+	in milliseconds), for one stock when StockID is not empty
+*/
+func getTransactionsForFI(FIID string, StockID string, fromDate string, toDate string, stub shim.ChaincodeStubInterface) ([]Transaction, error) {
+	var transactions []Transaction
+
+	if err := authorize(stub, RoleFI, FIID); err != nil {
+		return nil, err
+	}
+
+	from, err := msToTime(fromDate)
+	if err != nil {
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid from date "+fromDate)
+	}
+	to, err := msToTime(toDate)
+	if err != nil {
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid to date "+toDate)
+	}
+
+	index := TransactionsByFIIndex
+	attributes := []string{FIID}
+	if len(StockID) > 0 {
+		index = TransactionsByFIAndStockIndex
+		attributes = append(attributes, StockID)
+	}
+	startKey, err := createCompositeKey(index, append(attributes, formatIndexDate(from))...)
+	if err != nil {
+		return nil, err
+	}
+	endKey, err := createCompositeKey(index, append(attributes, formatIndexDate(to))...)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := getKeysInRange(stub, startKey, endKey+maxUnicodeRune)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		_, indexAttributes := splitCompositeKey(key)
+		transactionID := indexAttributes[len(indexAttributes)-1]
+		transaction, err := getTransaction(stub, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if transaction == nil {
+			fmt.Printf("Index %s refers to missing transaction %s\n", index, transactionID)
+			continue
+		}
+		transactions = append(transactions, *transaction)
+	}
+	if len(transactions) == 0 {
+		if err = checkPartyHasRecords(stub, TransactionsByFIIndex, FIID); err != nil {
+			return nil, err
+		}
+	}
+	return transactions, nil
+}
+
+// OrderAmendment keeps the terms of an order as they were before an amendment
+type OrderAmendment struct {
+	AmendmentDate time.Time     `json:"amendmentDate"` // time of the transaction amending the order
+	Quantity      int           `json:"quantity"`      // quantity before the amendment
+	LimitPrice    float32       `json:"limitPrice"`    // limit price before the amendment
+	OrderValidity OrderValidity `json:"orderValidity"` // validity before the amendment
+	ValidTill     string        `json:"validTill"`     // GTD expiry before the amendment
+}
+
+// OrderAmendmentRequest is sent by the FI to change the terms of an order; absent fields are left unchanged
+type OrderAmendmentRequest struct {
+	Quantity      *int           `json:"quantity"`
+	LimitPrice    *float32       `json:"limitPrice"`
+	OrderValidity *OrderValidity `json:"orderValidity"`
+	ValidTill     *string        `json:"validTill"`
+}
+
+// Returns the time of the current transaction, which is the same on every peer
+func getTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		fmt.Printf("Failed to get the transaction timestamp : %v\n", err)
+		return time.Time{}, err
+	}
+	if txTimestamp == nil {
+		return time.Time{}, ccerror.New(ccerror.Internal, "Transaction has no timestamp")
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
+}
+
+// Returns the order fiOrderID after checking that FIID placed it and that it is in one of statuses
+func getOrderForFI(stub shim.ChaincodeStubInterface, FIID string, fiOrderID string, statuses ...OrderStatus) (*FIOrder, error) {
+	if err := authorize(stub, RoleFI, FIID); err != nil {
+		return nil, err
+	}
+	fiOrder, err := getFIOrder(stub, fiOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if fiOrder == nil {
+		fmt.Printf("Order %s not found\n", fiOrderID)
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+fiOrderID)
+	}
+	if fiOrder.FIID != FIID {
+		fmt.Printf("Order %s was not placed by FI %s\n", fiOrderID, FIID)
+		return nil, ccerror.New(ccerror.Unauthorized, "FI "+FIID+" is not allowed to act on order "+fiOrderID)
+	}
+	allowed := false
+	for _, status := range statuses {
+		if fiOrder.Status == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		fmt.Printf("Order %s is already %s\n", fiOrderID, fiOrder.Status)
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+fiOrderID+" is already "+string(fiOrder.Status)+" and can no longer be changed")
+	}
+	if err = checkNotExpired(stub, fiOrder); err != nil {
+		return nil, err
+	}
+	return fiOrder, nil
+}
+
+// cancel what is left open of an order ==> args: FIID, fiOrderID
+// A partially filled order is left Executed for the quantity filled, the rest is recorded as its expiredQuantity.
+func (t *CapitalMarketChainCode) cancelOrder(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	previous, err := getOrderForFI(stub, args[0], args[1], OrderStatusNew, OrderStatusAcknowledged, OrderStatusPartiallyFilled)
+	if err != nil {
+		return nil, err
+	}
+	fiOrder := *previous
+	if err = fiOrder.cancel(); err != nil {
+		return nil, err
+	}
+	if err = putFIOrder(stub, fiOrder, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to cancel order "+args[1])
+	}
+	err = setLifecycleEvent(stub, EventOrderStatusChanged, []OrderEvent{newOrderEvent(fiOrder, previous)}, nil)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Order %s cancelled\n", fiOrder.FIOrderID)
+	return json.Marshal(&fiOrder)
+}
+
+// amend the quantity, limit price or validity of an order not yet executed by the broker
+// ==> args: FIID, fiOrderID, OrderAmendmentRequest JSON
+// An order resting in the order book keeps its time priority only when its quantity is lowered;
+// an order amended to IOC, or to a price that crosses the best order of the other side, leaves the
+// book so that the broker submits it again to trade what it can.
+func (t *CapitalMarketChainCode) amendOrder(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var request OrderAmendmentRequest
+
+	err := json.Unmarshal([]byte(args[2]), &request)
+	if err != nil {
+		fmt.Printf("Error unmarshalling amendment : %v\n", err)
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid amendment for order "+args[1])
+	}
+	if request.Quantity == nil && request.LimitPrice == nil && request.OrderValidity == nil && request.ValidTill == nil {
+		return nil, ccerror.New(ccerror.BadArgs, "Amendment for order "+args[1]+" changes nothing")
+	}
+	previous, err := getOrderForFI(stub, args[0], args[1], OrderStatusNew, OrderStatusAcknowledged)
+	if err != nil {
+		return nil, err
+	}
+	amendmentDate, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	fiOrder := *previous
+	fiOrder.Amendments = append(append([]OrderAmendment{}, previous.Amendments...), OrderAmendment{
+		AmendmentDate: amendmentDate,
+		Quantity:      previous.Quantity,
+		LimitPrice:    previous.LimitPrice,
+		OrderValidity: previous.OrderValidity,
+		ValidTill:     previous.ValidTill,
+	})
+	if request.Quantity != nil {
+		fiOrder.Quantity = *request.Quantity
+	}
+	if request.LimitPrice != nil {
+		fiOrder.LimitPrice = *request.LimitPrice
+	}
+	if request.OrderValidity != nil {
+		fiOrder.OrderValidity = *request.OrderValidity
+	}
+	if request.ValidTill != nil {
+		fiOrder.ValidTill = *request.ValidTill
+	}
+	if fieldErrors := validateFIOrder(fiOrder, amendmentDate); len(fieldErrors) > 0 {
+		return nil, OrderValidationError(fieldErrors).ccError()
+	}
+	fiOrder.ExpiryDate, err = orderExpiryDate(fiOrder)
+	if err != nil {
+		return nil, err
+	}
+	if fiOrder.isInBook() {
+		switch {
+		case fiOrder.OrderType == OrderTypeMarket || fiOrder.OrderValidity == OrderValidityIOC:
+			// such orders never rest in the book; the broker submits it again to trade what it can
+			fiOrder.BookSequence = ""
+		case fiOrder.LimitPrice != previous.LimitPrice || fiOrder.Quantity > previous.Quantity ||
+			fiOrder.OrderValidity != previous.OrderValidity || fiOrder.ValidTill != previous.ValidTill:
+			// the order loses its time priority, only lowering its quantity keeps it
+			fiOrder.BookSequence, err = nextBookSequence(stub)
+			if err != nil {
+				fmt.Printf("Error generating book sequence : %v\n", err)
+				return nil, ccerror.New(ccerror.Internal, "Failed to amend order "+args[1])
+			}
+		}
+	}
+	if fiOrder.isInBook() && fiOrder.LimitPrice != previous.LimitPrice {
+		crossed, err := crossesBook(stub, fiOrder, amendmentDate)
+		if err != nil {
+			return nil, err
+		}
+		if crossed {
+			// resting there would leave the book crossed
+			fiOrder.BookSequence = ""
+		}
+	}
+	if err = putFIOrder(stub, fiOrder, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to amend order "+args[1])
+	}
+	fmt.Printf("Order %s amended\n", fiOrder.FIOrderID)
+	return json.Marshal(&fiOrder)
+}
+
+// expire every open order whose validity has lapsed at the time of the transaction ==> no args
+// Returns the IDs of the orders expired. Any FI, broker or custodian bank may call it, as it only
+// applies the validity each order was placed with.
+func (t *CapitalMarketChainCode) expireOrders(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	expiredOrderIDs := []string{}
+	var orderEvents []OrderEvent
+
+	if err := authorizeAnyRole(stub); err != nil {
+		return nil, err
+	}
+	now, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range []OrderStatus{OrderStatusNew, OrderStatusAcknowledged, OrderStatusPartiallyFilled} {
+		fiOrders, err := getOrdersByIndex(stub, OrdersByStatusIndex, string(status))
+		if err != nil {
+			return nil, err
+		}
+		for i := range fiOrders {
+			previous := &fiOrders[i]
+			if !previous.isExpiredAt(now) {
+				continue
+			}
+			fiOrder := *previous
+			if err = fiOrder.expire(); err != nil {
+				return nil, err
+			}
+			if err = putFIOrder(stub, fiOrder, previous); err != nil {
+				return nil, ccerror.New(ccerror.Internal, "Failed to expire order "+fiOrder.FIOrderID)
+			}
+			expiredOrderIDs = append(expiredOrderIDs, fiOrder.FIOrderID)
+			orderEvents = append(orderEvents, newOrderEvent(fiOrder, previous))
+		}
+	}
+	if len(orderEvents) > 0 {
+		if err = setLifecycleEvent(stub, EventOrderStatusChanged, orderEvents, nil); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Printf("Expired orders %v\n", expiredOrderIDs)
+	return json.Marshal(&expiredOrderIDs)
+}
+
+// AllocationStatus is the state of the allocation instruction of an order
+type AllocationStatus string
+
+// AllocationInstruction statuses
+const (
+	AllocationStatusPending  AllocationStatus = "Pending"  // sent by the FI, waiting for the custodian
+	AllocationStatusAffirmed AllocationStatus = "Affirmed" // affirmed by the custodian, the order can settle
+)
+
+// Allocation assigns part of an executed order to an account of the FI
+type Allocation struct {
+	AccountID string `json:"accountID"` // Account ID of the FI
+	Quantity  int    `json:"quantity"`  // quantity of stock allocated to the account
+}
+
+// AllocationInstruction tells the custodian bank how to split an executed order over the FI's accounts
+type AllocationInstruction struct {
+	Allocations     []Allocation     `json:"allocations"`     // split of the executed quantity
+	Status          AllocationStatus `json:"status"`          // Pending until the custodian affirms it
+	InstructionDate time.Time        `json:"instructionDate"` // time the FI sent the instruction
+	AffirmationDate time.Time        `json:"affirmationDate"` // time the custodian affirmed it
+}
+
+// Refuses fiOrder when it has no allocation instruction or when the allocations of its instruction
+// do not add up to its executed quantity
+func checkAllocatedQuantity(fiOrder FIOrder) error {
+	if fiOrder.AllocationInstruction == nil {
+		return ccerror.New(ccerror.InvalidState, "Order "+fiOrder.FIOrderID+" has no allocation instruction")
+	}
+	allocatedQuantity := 0
+	for _, allocation := range fiOrder.AllocationInstruction.Allocations {
+		if allocation.Quantity <= 0 {
+			return ccerror.New(ccerror.InvalidState, "Order "+fiOrder.FIOrderID+" has an allocation without a positive quantity")
+		}
+		allocatedQuantity = allocatedQuantity + allocation.Quantity
+	}
+	if allocatedQuantity != fiOrder.ExecutedQuantity {
+		return ccerror.Errorf(ccerror.InvalidState, "Allocations of order %s total %d, the executed quantity is %d",
+			fiOrder.FIOrderID, allocatedQuantity, fiOrder.ExecutedQuantity)
+	}
+	return nil
+}
+
+// send allocation instructions for an executed order to its custodian
+// ==> args: FIID, fiOrderID, []Allocation JSON
+// A pending instruction is replaced by a new one; an affirmed one can no longer be changed.
+func (t *CapitalMarketChainCode) sendAllocationInstruction(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var allocations []Allocation
+
+	if err := authorize(stub, RoleFI, args[0]); err != nil {
+		return nil, err
+	}
+	err := json.Unmarshal([]byte(args[2]), &allocations)
+	if err != nil {
+		fmt.Printf("Error unmarshalling allocations : %v\n", err)
+		return nil, ccerror.New(ccerror.BadArgs, "Invalid allocations for order "+args[1])
+	}
+	previous, err := getFIOrder(stub, args[1])
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+args[1])
+	}
+	if previous.FIID != args[0] {
+		return nil, ccerror.New(ccerror.Unauthorized, "FI "+args[0]+" is not allowed to act on order "+args[1])
+	}
+	if previous.Status != OrderStatusExecuted {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" is "+string(previous.Status)+", only "+string(OrderStatusExecuted)+" orders can be allocated")
+	}
+	if len(previous.CustodianBankID) == 0 {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" has no custodian bank to allocate with")
+	}
+	if previous.AllocationInstruction != nil && previous.AllocationInstruction.Status == AllocationStatusAffirmed {
+		return nil, ccerror.New(ccerror.InvalidState, "Allocation of order "+args[1]+" is already affirmed")
+	}
+
+	allocatedQuantity := 0
+	for i, allocation := range allocations {
+		if len(allocation.AccountID) == 0 || allocation.Quantity <= 0 {
+			return nil, ccerror.New(ccerror.BadArgs, "Allocation "+strconv.Itoa(i)+" of order "+args[1]+" needs an accountID and a positive quantity")
+		}
+		allocatedQuantity = allocatedQuantity + allocation.Quantity
+	}
+	if allocatedQuantity != previous.ExecutedQuantity {
+		return nil, ccerror.Errorf(ccerror.BadArgs, "Allocations of order %s total %d, the executed quantity is %d", args[1], allocatedQuantity, previous.ExecutedQuantity)
+	}
+	instructionDate, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	fiOrder := *previous
+	fiOrder.AllocationInstruction = &AllocationInstruction{
+		Allocations:     allocations,
+		Status:          AllocationStatusPending,
+		InstructionDate: instructionDate,
+	}
+	if err = putFIOrder(stub, fiOrder, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to record allocations of order "+args[1])
+	}
+	fmt.Printf("Allocations of order %s sent to custodian %s\n", fiOrder.FIOrderID, fiOrder.CustodianBankID)
+	return json.Marshal(&fiOrder)
+}
+
+// affirm the pending allocation instruction of an order ==> args: custodianBankID, fiOrderID
+func (t *CapitalMarketChainCode) affirmAllocation(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if err := authorize(stub, RoleCustodian, args[0]); err != nil {
+		return nil, err
+	}
+	previous, err := getFIOrder(stub, args[1])
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find order "+args[1])
+	}
+	if previous.CustodianBankID != args[0] {
+		return nil, ccerror.New(ccerror.Unauthorized, "Custodian "+args[0]+" is not allowed to act on order "+args[1])
+	}
+	if previous.AllocationInstruction == nil || previous.AllocationInstruction.Status != AllocationStatusPending {
+		return nil, ccerror.New(ccerror.InvalidState, "Order "+args[1]+" has no pending allocation instruction")
+	}
+	affirmationDate, err := getTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	fiOrder := *previous
+	instruction := *previous.AllocationInstruction
+	instruction.Status = AllocationStatusAffirmed
+	instruction.AffirmationDate = affirmationDate
+	fiOrder.AllocationInstruction = &instruction
+	if err = putFIOrder(stub, fiOrder, previous); err != nil {
+		return nil, ccerror.New(ccerror.Internal, "Failed to affirm allocations of order "+args[1])
+	}
+	fmt.Printf("Allocations of order %s affirmed\n", fiOrder.FIOrderID)
+	return json.Marshal(&fiOrder)
+}
+
+// Error codes of a QueryResponse
+const (
+	QueryCodeOK       = "OK"        // Data holds the records found
+	QueryCodeNoMatch  = "NO_MATCH"  // the party has records but none match the query
+	QueryCodeNotFound = "NOT_FOUND" // the party has no records of the kind queried at all
+)
+
+// QueryResponse is returned by every query. Queries that fail, for instance on bad arguments or
+// a caller not allowed to see the records, return an error instead.
+type QueryResponse struct {
+	Data      interface{} `json:"data"`               // list of records found, empty when there are none
+	Count     int         `json:"count"`              // number of records in Data
+	Bookmark  string      `json:"bookmark,omitempty"` // bookmark of the next page, for searchOrders
+	ErrorCode string      `json:"errorCode"`          // one of the QueryCode values
+}
+
+// Builds the QueryResponse for the count records in data returned by a query with err.
+// errNoRecords becomes a NOT_FOUND response, any other error is returned as it is.
+func newQueryResponse(data interface{}, count int, err error) (*QueryResponse, error) {
+	response := &QueryResponse{Data: data, Count: count, ErrorCode: QueryCodeOK}
+	if err == errNoRecords {
+		response.Count = 0
+		response.ErrorCode = QueryCodeNotFound
+	} else if err != nil {
+		return nil, err
+	} else if count == 0 {
+		response.ErrorCode = QueryCodeNoMatch
+	}
+	if response.Count == 0 {
+		response.Data = []interface{}{}
+	}
+	return response, nil
+}
+
+// Returns as JSON the QueryResponse of a query that returned the count records in data with err,
+// logging the errors other than errNoRecords
+func queryResponse(description string, data interface{}, count int, err error) ([]byte, error) {
+	if err != nil && err != errNoRecords {
+		fmt.Printf("Error getting %s : %v\n", description, err)
+	}
+	response, err := newQueryResponse(data, count, err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(response)
+}
+
+// Returns the functions of the chaincode
+func (t *CapitalMarketChainCode) routes() *router.Router {
+	return router.New().
+		Invoke("createOrdersByFI", "Creates a batch of orders for the FI, all or none",
+			[]router.Arg{{Name: "FIID", Type: router.String}, {Name: "orders", Type: router.Array}}, t.createOrdersByFI).
+		Invoke("cancelOrder", "Cancels what is left open of an order not fully executed",
+			router.Strings("FIID", "fiOrderID"), t.cancelOrder).
+		Invoke("amendOrder", "Changes the quantity, limit price or validity of an order not yet executed",
+			[]router.Arg{{Name: "FIID", Type: router.String}, {Name: "fiOrderID", Type: router.String}, {Name: "amendment", Type: router.Object}}, t.amendOrder).
+		Invoke("expireOrders", "Expires the open orders past their expiry date",
+			nil, t.expireOrders).
+		Invoke("acknowledgeOrder", "Acknowledges the receipt of an order by its broker",
+			router.Strings("brokerID", "fiOrderID"), t.acknowledgeOrder).
+		Invoke("confirmOrder", "Records a fill of an order executed on the exchange",
+			[]router.Arg{{Name: "brokerID", Type: router.String}, {Name: "fiOrderID", Type: router.String}, {Name: "confirmation", Type: router.Object}}, t.confirmOrder).
+		Invoke("submitOrderToBook", "Matches an acknowledged order against the order book of its exchange and stock",
+			router.Strings("brokerID", "fiOrderID"), t.submitOrderToBook).
+		Invoke("rejectOrder", "Rejects an order by its broker",
+			router.Strings("brokerID", "fiOrderID", "reason"), t.rejectOrder).
+		Invoke("sendAllocationInstruction", "Sends the split of an executed order over the FI's accounts to its custodian",
+			[]router.Arg{{Name: "FIID", Type: router.String}, {Name: "fiOrderID", Type: router.String}, {Name: "allocations", Type: router.Array}}, t.sendAllocationInstruction).
+		Invoke("affirmAllocation", "Affirms the allocation instruction of an order by its custodian",
+			router.Strings("custodianBankID", "fiOrderID"), t.affirmAllocation).
+		Invoke("createSettlementTrade", "Groups executed orders with affirmed allocations in a trade to settle",
+			[]router.Arg{{Name: "custodianBankID", Type: router.String}, {Name: "trade", Type: router.Object}}, t.createSettlementTrade).
+		Invoke("settleTrade", "Settles a pending trade and books its transactions",
+			[]router.Arg{{Name: "custodianBankID", Type: router.String}, {Name: "tradeObjectID", Type: router.String}, {Name: "settlementDate", Type: router.Number}}, t.settleTrade).
+		Query("getAllOrdersForFIBasedOnStatus", "Orders of a FI, in one status or all when status is empty",
+			router.Strings("FIID", "status"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				allOrders, err := getAllOrdersForFIBasedOnStatus(args[0], args[1], stub)
+				return queryResponse("All Orders for FI "+args[0], allOrders, len(allOrders), err)
+			}).
+		Query("getAllOrdersForBrokerBasedOnStatus", "Orders routed to a broker, in one status or all when status is empty",
+			router.Strings("brokerID", "status"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				allOrders, err := getAllOrdersForBrokerBasedOnStatus(args[0], args[1], stub)
+				return queryResponse("All Orders for Broker "+args[0], allOrders, len(allOrders), err)
+			}).
+		Query("getAllOrdersForCustodianBasedOnStatus", "Orders held with a custodian bank, in one status or all when status is empty",
+			router.Strings("custodianBankID", "status"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				allOrders, err := getAllOrdersForCustodianBasedOnStatus(args[0], args[1], stub)
+				return queryResponse("All Orders for Custodian "+args[0], allOrders, len(allOrders), err)
+			}).
+		Query("searchOrders", "A page of the orders matching a filter",
+			[]router.Arg{{Name: "filter", Type: router.Object}}, func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				var filter OrderSearchFilter
+				err := json.Unmarshal([]byte(args[0]), &filter)
+				if err != nil {
+					fmt.Printf("Error unmarshalling search filter : %v\n", err)
+					return nil, ccerror.New(ccerror.BadArgs, "Invalid search filter")
+				}
+				result, err := searchOrders(filter, stub)
+				if err != nil && err != errNoRecords {
+					fmt.Printf("Error searching orders : %v\n", err)
+				}
+				response, err := newQueryResponse(result.Orders, len(result.Orders), err)
+				if err != nil {
+					return nil, err
+				}
+				response.Bookmark = result.Bookmark
+				return json.Marshal(response)
+			}).
+		Query("getOrderBook", "Orders resting in the order book of an exchange and stock, bids then asks",
+			router.Strings("exchange", "stockID"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				bookEntries, err := getOrderBook(args[0], args[1], stub)
+				return queryResponse("order book of "+args[1]+" on "+args[0], bookEntries, len(bookEntries), err)
+			}).
+		Query("getExecutionsForOrder", "Executions of an order matched in an order book",
+			router.Strings("fiOrderID"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				executions, err := getExecutionsForOrder(args[0], stub)
+				return queryResponse("executions of order "+args[0], executions, len(executions), err)
+			}).
+		Query("getAllTradesBasedOnStatus", "Trades of the calling custodian, in one status or all when status is empty",
+			router.Strings("status"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				allTrades, err := getAllTradesBasedOnStatus(args[0], stub)
+				return queryResponse("All Trades with status "+args[0], allTrades, len(allTrades), err)
+			}).
+		Query("getAllTradesForCustodianBasedOnStatus", "Trades of a custodian, in one status or all when status is empty",
+			router.Strings("custodianBankID", "status"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				allTrades, err := getAllTradesForCustodianBasedOnStatus(args[0], args[1], stub)
+				return queryResponse("All Trades for Custodian "+args[0], allTrades, len(allTrades), err)
+			}).
+		Query("getHoldingsForFI", "Holdings of a FI, in one stock or all when stockID is empty",
+			router.Strings("FIID", "stockID"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				holdings, err := getHoldingsForFI(args[0], args[1], stub)
+				return queryResponse("holdings for FI "+args[0], holdings, len(holdings), err)
+			}).
+		Query("getTransactionsForFI", "Transactions of a FI settled between two dates in milliseconds, in one stock or all when stockID is empty",
+			[]router.Arg{{Name: "FIID", Type: router.String}, {Name: "stockID", Type: router.String}, {Name: "fromDate", Type: router.Number}, {Name: "toDate", Type: router.Number}},
+			func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				transactions, err := getTransactionsForFI(args[0], args[1], args[2], args[3], stub)
+				return queryResponse("transactions for FI "+args[0], transactions, len(transactions), err)
+			})
+}
+
+// Query function
+// Every error returned is a *ccerror.Error, ledger failures being reported as internal.
+func (t *CapitalMarketChainCode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	fmt.Println("Query running. Function: " + function)
+	fmt.Printf("args: %s\n", args)
+	return t.routes().Handle(stub, router.Query, function, args)
+}
+
+// Invoke function
+// Every error returned is a *ccerror.Error, ledger failures being reported as internal.
+func (t *CapitalMarketChainCode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	fmt.Println("Invoke running. Function: " + function)
+	fmt.Printf("args: %s\n", args)
+	return t.routes().Handle(stub, router.Invoke, function, args)
+}
+
+func main() {
+	err := shim.Start(new(CapitalMarketChainCode))
+	if err != nil {
+		fmt.Printf("Error starting Capital Market chaincode: %s\n", err)
+	}
+}
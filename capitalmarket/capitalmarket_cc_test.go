@@ -0,0 +1,855 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+)
+
+// testStub gives the MockStub the enrollment certificate attributes and the transaction
+// time it does not provide, so that the chaincode can be called as a FI, broker or custodian
+type testStub struct {
+	*shim.MockStub
+	attributes map[string]string
+	now        time.Time
+	events     []chaincodeEvent // events set by the last invoke
+}
+
+// chaincodeEvent is an event set with SetEvent
+type chaincodeEvent struct {
+	name    string
+	payload []byte
+}
+
+func newTestStub() *testStub {
+	return &testStub{
+		MockStub: shim.NewMockStub("capitalmarket", new(CapitalMarketChainCode)),
+		now:      time.Date(2017, 7, 14, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func (s *testStub) ReadCertAttribute(attributeName string) ([]byte, error) {
+	return []byte(s.attributes[attributeName]), nil
+}
+
+func (s *testStub) VerifyAttribute(attributeName string, attributeValue []byte) (bool, error) {
+	value, ok := s.attributes[attributeName]
+	return ok && value == string(attributeValue), nil
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+func (s *testStub) SetEvent(name string, payload []byte) error {
+	s.events = append(s.events, chaincodeEvent{name, payload})
+	return nil
+}
+
+// makes the following calls as role acting for partyID
+func (s *testStub) as(role Role, partyID string) *testStub {
+	s.attributes = map[string]string{RoleAttribute: string(role), roleIDAttributes[role]: partyID}
+	return s
+}
+
+func (s *testStub) invoke(function string, args ...string) ([]byte, error) {
+	s.events = nil
+	return new(CapitalMarketChainCode).Invoke(s, function, args)
+}
+
+func (s *testStub) query(function string, args ...string) ([]byte, error) {
+	return new(CapitalMarketChainCode).Query(s, function, args)
+}
+
+// creates the order as FI1, acknowledges it as B1 and returns its ID
+func (s *testStub) acknowledgedOrder(t *testing.T, order string) string {
+	bytes, err := s.as(RoleFI, "FI1").invoke("createOrdersByFI", "FI1", "["+order+"]")
+	if err != nil {
+		t.Fatalf("createOrdersByFI returned %v", err)
+	}
+	var results []OrderResult
+	if err = json.Unmarshal(bytes, &results); err != nil || len(results) != 1 {
+		t.Fatalf("createOrdersByFI returned %s", bytes)
+	}
+	if _, err = s.as(RoleBroker, "B1").invoke("acknowledgeOrder", "B1", results[0].FIOrderID); err != nil {
+		t.Fatalf("acknowledgeOrder returned %v", err)
+	}
+	return results[0].FIOrderID
+}
+
+// submits an acknowledged order to the book as B1
+func (s *testStub) submitToBook(t *testing.T, fiOrderID string) OrderBookResult {
+	var result OrderBookResult
+
+	s.now = s.now.Add(time.Second)
+	bytes, err := s.as(RoleBroker, "B1").invoke("submitOrderToBook", "B1", fiOrderID)
+	if err != nil {
+		t.Fatalf("submitOrderToBook of %s returned %v", fiOrderID, err)
+	}
+	if err = json.Unmarshal(bytes, &result); err != nil {
+		t.Fatalf("submitOrderToBook returned %s", bytes)
+	}
+	return result
+}
+
+// returns the entries of the order book of IBM on NSE
+func (s *testStub) orderBook(t *testing.T) []BookEntry {
+	var response struct {
+		Data []BookEntry `json:"data"`
+	}
+
+	bytes, err := s.as(RoleBroker, "B1").query("getOrderBook", "NSE", "IBM")
+	if err != nil || json.Unmarshal(bytes, &response) != nil {
+		t.Fatalf("getOrderBook returned %s %v", bytes, err)
+	}
+	return response.Data
+}
+
+// calls the invoke function, failing t when it returns an error
+func (s *testStub) mustInvoke(t *testing.T, function string, args ...string) []byte {
+	bytes, err := s.invoke(function, args...)
+	if err != nil {
+		t.Fatalf("%s%v returned %v", function, args, err)
+	}
+	return bytes
+}
+
+// creates the order as FI1 and has B1 execute all of it at price, returning its ID
+func (s *testStub) executedOrder(t *testing.T, side OrderSide, quantity int, price string) string {
+	fiOrderID := s.acknowledgedOrder(t, limitOrder(side, quantity, price, OrderValidityGTC))
+	s.as(RoleBroker, "B1").mustInvoke(t, "confirmOrder", "B1", fiOrderID,
+		`{"executedPrice":`+price+`,"executedQuantity":`+strconv.Itoa(quantity)+`,"exchangeTradeNumber":"X`+fiOrderID+`"}`)
+	return fiOrderID
+}
+
+// sends the allocations of an executed order as FI1 and has C1 affirm them
+func (s *testStub) affirmedOrder(t *testing.T, fiOrderID string, allocations string) {
+	s.as(RoleFI, "FI1").mustInvoke(t, "sendAllocationInstruction", "FI1", fiOrderID, allocations)
+	s.as(RoleCustodian, "C1").mustInvoke(t, "affirmAllocation", "C1", fiOrderID)
+}
+
+// creates a trade of the orders as C1 and returns its ID
+func (s *testStub) settlementTrade(t *testing.T, fiOrderIDs ...string) string {
+	var tradeObject TradeObject
+
+	request, _ := json.Marshal(SettlementTradeRequest{OderTradeNumber: "T1", FIOrderIDs: fiOrderIDs})
+	bytes := s.as(RoleCustodian, "C1").mustInvoke(t, "createSettlementTrade", "C1", string(request))
+	if err := json.Unmarshal(bytes, &tradeObject); err != nil {
+		t.Fatalf("createSettlementTrade returned %s", bytes)
+	}
+	return tradeObject.TradeObjectID
+}
+
+// returns the single event set by the last invoke, failing t when it is not a LifecycleEvent of eventType
+func (s *testStub) lifecycleEvent(t *testing.T, eventType EventType) LifecycleEvent {
+	var event LifecycleEvent
+
+	if len(s.events) != 1 {
+		t.Fatalf("%d events set, expecting one %s event", len(s.events), eventType)
+	}
+	if err := json.Unmarshal(s.events[0].payload, &event); err != nil || s.events[0].name != string(eventType) ||
+		event.Type != eventType || !event.Timestamp.Equal(s.now) {
+		t.Fatalf("event %s is %s, expecting a %s event at %v", s.events[0].name, s.events[0].payload, eventType, s.now)
+	}
+	return event
+}
+
+func limitOrder(side OrderSide, quantity int, price string, validity OrderValidity) string {
+	return `{"brokerID":"B1","custodianBankID":"C1","stockID":"IBM","exchange":"NSE","side":"` + string(side) +
+		`","orderType":"Limit","quantity":` + strconv.Itoa(quantity) + `,"limitPrice":` + price + `,"orderValidity":"` + string(validity) + `"}`
+}
+
+// TestBookPriceKey test that book keys sort the best price first on each side
+func TestBookPriceKey(t *testing.T) {
+	prices := []float32{99, 100, 100.5, 100.0001, 101}
+	for i := 1; i < len(prices); i++ {
+		lower, higher := prices[i-1], prices[i]
+		if lower > higher {
+			lower, higher = higher, lower
+		}
+		if !(bookPriceKey(OrderSideSell, lower) < bookPriceKey(OrderSideSell, higher)) {
+			t.Fatalf("sell at %v should come before sell at %v", lower, higher)
+		}
+		if !(bookPriceKey(OrderSideBuy, higher) < bookPriceKey(OrderSideBuy, lower)) {
+			t.Fatalf("buy at %v should come before buy at %v", higher, lower)
+		}
+	}
+	if bookPriceKey(OrderSideSell, 100) != bookPriceKey(OrderSideSell, 100.00001) {
+		t.Fatalf("prices below a tick apart should share a key")
+	}
+}
+
+// TestSubmitOrderToBook test partial fills and price-time priority in the order book
+func TestSubmitOrderToBook(t *testing.T) {
+	s := newTestStub()
+	sell101 := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 10, "101", OrderValidityGTC))
+	sell100 := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 10, "100", OrderValidityGTC))
+	sell100Later := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 5, "100", OrderValidityGTC))
+	for _, fiOrderID := range []string{sell101, sell100, sell100Later} {
+		if result := s.submitToBook(t, fiOrderID); len(result.Executions) != 0 || result.Order.Status != OrderStatusAcknowledged {
+			t.Fatalf("sell %s should rest in the book, got %v", fiOrderID, result)
+		}
+	}
+
+	buy := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 18, "100.5", OrderValidityGTC))
+	result := s.submitToBook(t, buy)
+	if len(result.Executions) != 2 ||
+		result.Executions[0].SellFIOrderID != sell100 || result.Executions[0].Quantity != 10 || result.Executions[0].Price != 100 ||
+		result.Executions[1].SellFIOrderID != sell100Later || result.Executions[1].Quantity != 5 || result.Executions[1].Price != 100 {
+		t.Fatalf("buy matched %v", result.Executions)
+	}
+	if result.Order.Status != OrderStatusPartiallyFilled || result.Order.ExecutedQuantity != 15 || result.Order.ExecutedPrice != 100 {
+		t.Fatalf("buy ended %s with %d at %v", result.Order.Status, result.Order.ExecutedQuantity, result.Order.ExecutedPrice)
+	}
+	expected := []BookEntry{
+		{Side: OrderSideBuy, FIOrderID: buy, LimitPrice: 100.5, OpenQuantity: 3},
+		{Side: OrderSideSell, FIOrderID: sell101, LimitPrice: 101, OpenQuantity: 10},
+	}
+	if book := s.orderBook(t); !reflect.DeepEqual(book, expected) {
+		t.Fatalf("book is %v, expecting %v", book, expected)
+	}
+
+	// an IOC remainder expires instead of resting
+	sellIOC := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 5, "100", OrderValidityIOC))
+	result = s.submitToBook(t, sellIOC)
+	if len(result.Executions) != 1 || result.Executions[0].BuyFIOrderID != buy || result.Executions[0].Price != 100.5 ||
+		result.Order.Status != OrderStatusExecuted || result.Order.ExecutedQuantity != 3 || result.Order.ExpiredQuantity != 2 {
+		t.Fatalf("IOC sell ended %v", result)
+	}
+	if _, err := s.as(RoleBroker, "B1").invoke("submitOrderToBook", "B1", sell101); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("submitting an order resting in the book returned %v", err)
+	}
+}
+
+// TestAmendRestingOrder test the time priority of an order amended while it rests in the book
+func TestAmendRestingOrder(t *testing.T) {
+	s := newTestStub()
+	// second rests before first, at a worse price
+	second := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 10, "11", OrderValidityGTC))
+	first := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 10, "10", OrderValidityGTC))
+	s.submitToBook(t, second)
+	s.submitToBook(t, first)
+
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", second, `{"limitPrice":10}`); err != nil {
+		t.Fatalf("amendOrder returned %v", err)
+	}
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", first, `{"quantity":5}`); err != nil {
+		t.Fatalf("amendOrder returned %v", err)
+	}
+	expected := []BookEntry{
+		{Side: OrderSideSell, FIOrderID: first, LimitPrice: 10, OpenQuantity: 5},
+		{Side: OrderSideSell, FIOrderID: second, LimitPrice: 10, OpenQuantity: 10},
+	}
+	if book := s.orderBook(t); !reflect.DeepEqual(book, expected) {
+		t.Fatalf("book is %v, expecting %v", book, expected)
+	}
+
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", first, `{"quantity":20}`); err != nil {
+		t.Fatalf("amendOrder returned %v", err)
+	}
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", second, `{"orderValidity":"IOC"}`); err != nil {
+		t.Fatalf("amendOrder returned %v", err)
+	}
+	expected = []BookEntry{{Side: OrderSideSell, FIOrderID: first, LimitPrice: 10, OpenQuantity: 20}}
+	if book := s.orderBook(t); !reflect.DeepEqual(book, expected) {
+		t.Fatalf("book is %v, expecting %v", book, expected)
+	}
+}
+
+// TestAmendCrossingOrder test that an order amended to a price crossing the other side leaves the book
+func TestAmendCrossingOrder(t *testing.T) {
+	s := newTestStub()
+	buy := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC))
+	sell := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 10, "101", OrderValidityGTC))
+	s.submitToBook(t, buy)
+	s.submitToBook(t, sell)
+
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", sell, `{"limitPrice":99}`); err != nil {
+		t.Fatalf("amendOrder returned %v", err)
+	}
+	expected := []BookEntry{{Side: OrderSideBuy, FIOrderID: buy, LimitPrice: 100, OpenQuantity: 10}}
+	if book := s.orderBook(t); !reflect.DeepEqual(book, expected) {
+		t.Fatalf("book is %v, expecting %v", book, expected)
+	}
+
+	result := s.submitToBook(t, sell)
+	if len(result.Executions) != 1 || result.Executions[0].Price != 100 || result.Order.Status != OrderStatusExecuted {
+		t.Fatalf("submitOrderToBook of the amended order returned %+v", result)
+	}
+	if book := s.orderBook(t); len(book) != 0 {
+		t.Fatalf("book is %v, expecting it empty", book)
+	}
+}
+
+// TestTransitionTo test the order status transitions
+func TestTransitionTo(t *testing.T) {
+	fiOrder := FIOrder{FIOrderID: "1", Status: OrderStatusNew}
+	if err := fiOrder.transitionTo(OrderStatusAcknowledged); err != nil || fiOrder.Status != OrderStatusAcknowledged {
+		t.Fatalf("New to Acknowledged returned %v, status %s", err, fiOrder.Status)
+	}
+	if err := fiOrder.transitionTo(OrderStatusSettled); ccerror.CodeOf(err) != ccerror.InvalidState || fiOrder.Status != OrderStatusAcknowledged {
+		t.Fatalf("Acknowledged to Settled returned %v, status %s", err, fiOrder.Status)
+	}
+	for _, status := range []OrderStatus{OrderStatusSettled, OrderStatusCancelled, OrderStatusRejected, OrderStatusExpired} {
+		fiOrder.Status = status
+		if err := fiOrder.transitionTo(OrderStatusNew); ccerror.CodeOf(err) != ccerror.InvalidState {
+			t.Fatalf("%s should be final, transition returned %v", status, err)
+		}
+	}
+}
+
+// TestCancelOrder test that cancelling keeps the fills of a partially filled order
+func TestCancelOrder(t *testing.T) {
+	s := newTestStub()
+	var fiOrder FIOrder
+
+	unfilled := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC))
+	bytes := s.as(RoleFI, "FI1").mustInvoke(t, "cancelOrder", "FI1", unfilled)
+	if err := json.Unmarshal(bytes, &fiOrder); err != nil || fiOrder.Status != OrderStatusCancelled || fiOrder.ExpiredQuantity != 10 {
+		t.Fatalf("cancelOrder of an unfilled order returned %s", bytes)
+	}
+
+	buy := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC))
+	sell := s.acknowledgedOrder(t, limitOrder(OrderSideSell, 4, "100", OrderValidityGTC))
+	s.submitToBook(t, buy)
+	s.submitToBook(t, sell)
+	bytes = s.as(RoleFI, "FI1").mustInvoke(t, "cancelOrder", "FI1", buy)
+	fiOrder = FIOrder{}
+	if err := json.Unmarshal(bytes, &fiOrder); err != nil || fiOrder.Status != OrderStatusExecuted ||
+		fiOrder.ExecutedQuantity != 4 || fiOrder.ExpiredQuantity != 6 {
+		t.Fatalf("cancelOrder of a partially filled order returned %s", bytes)
+	}
+	if book := s.orderBook(t); len(book) != 0 {
+		t.Fatalf("book is %v, expecting it empty", book)
+	}
+
+	if _, err := s.as(RoleFI, "FI1").invoke("cancelOrder", "FI1", buy); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("cancelOrder of an executed order returned %v", err)
+	}
+	if _, err := s.as(RoleFI, "FI1").invoke("amendOrder", "FI1", sell, `{"quantity":2}`); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("amendOrder of an executed order returned %v", err)
+	}
+}
+
+// TestOrderExpiryDate test the expiry date of each order validity
+func TestOrderExpiryDate(t *testing.T) {
+	creationDate := time.Date(2017, 7, 14, 10, 0, 0, 0, time.UTC)
+	endOfDay := time.Date(2017, 7, 15, 0, 0, 0, 0, time.UTC)
+	validTill := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	for validity, expected := range map[OrderValidity]time.Time{
+		OrderValidityDay: endOfDay,
+		OrderValidityIOC: endOfDay,
+		OrderValidityGTC: {},
+		OrderValidityGTD: validTill,
+	} {
+		fiOrder := FIOrder{CreationDate: creationDate, OrderValidity: validity}
+		if validity == OrderValidityGTD {
+			fiOrder.ValidTill = strconv.FormatInt(validTill.UnixNano()/int64(time.Millisecond), 10)
+		}
+		expiryDate, err := orderExpiryDate(fiOrder)
+		if err != nil || !expiryDate.Equal(expected) {
+			t.Fatalf("%s order expires on %v %v, expecting %v", validity, expiryDate, err, expected)
+		}
+	}
+	if _, err := orderExpiryDate(FIOrder{CreationDate: creationDate, OrderValidity: OrderValidityGTD}); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("GTD order without validTill returned %v", err)
+	}
+	if _, err := orderExpiryDate(FIOrder{CreationDate: creationDate, OrderValidity: "WEEK"}); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("unknown validity returned %v", err)
+	}
+}
+
+// TestValidateFIOrder test the fields reported invalid on a submitted order
+func TestValidateFIOrder(t *testing.T) {
+	now := time.Date(2017, 7, 14, 10, 0, 0, 0, time.UTC)
+	fiOrder := FIOrder{FIID: "FI1", BrokerID: "B1", StockID: "IBM", Quantity: 10, Side: OrderSideBuy,
+		OrderType: OrderTypeLimit, LimitPrice: 100, OrderValidity: OrderValidityDay, CreationDate: now}
+	if fieldErrors := validateFIOrder(fiOrder, now); len(fieldErrors) != 0 {
+		t.Fatalf("valid order returned %v", fieldErrors)
+	}
+
+	fiOrder = FIOrder{Quantity: -1, Side: "Hold", OrderType: OrderTypeMarket, LimitPrice: 100, OrderValidity: OrderValidityGTD,
+		ValidTill: strconv.FormatInt(now.Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10), CreationDate: now}
+	var fields []string
+	for _, fieldError := range validateFIOrder(fiOrder, now) {
+		fields = append(fields, fieldError.Field)
+	}
+	expected := []string{"fiID", "brokerID", "stockID", "quantity", "side", "limitPrice", "validTill"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("invalid order returned fields %v, expecting %v", fields, expected)
+	}
+
+	err := validateFIOrders([]FIOrder{{ExecutedQuantity: 9, BookSequence: "000000000000000001"}}, now)
+	details, _ := err.(*ccerror.Error).Details.([]FieldError)
+	fields = nil
+	for _, fieldError := range details {
+		if fieldError.Message == "is set by the chaincode and must be empty" {
+			fields = append(fields, fieldError.Field)
+		}
+	}
+	if expected = []string{"executedQuantity", "bookSequence"}; !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("order with server-owned fields returned %v", err)
+	}
+}
+
+// TestCreateOrdersBatch test the results of a batch and that a failing batch writes nothing
+func TestCreateOrdersBatch(t *testing.T) {
+	s := newTestStub()
+	withRef := func(ref string, quantity int) string {
+		return strings.Replace(limitOrder(OrderSideBuy, quantity, "100", OrderValidityGTC), "{", `{"clientOrderRef":"`+ref+`",`, 1)
+	}
+
+	var results []OrderResult
+	bytes := s.as(RoleFI, "FI1").mustInvoke(t, "createOrdersByFI", "FI1", "["+withRef("R1", 10)+","+withRef("R2", 5)+"]")
+	expected := []OrderResult{
+		{ClientOrderRef: "R1", FIOrderID: "10001", Status: OrderStatusNew},
+		{ClientOrderRef: "R2", FIOrderID: "10002", Status: OrderStatusNew},
+	}
+	if err := json.Unmarshal(bytes, &results); err != nil || !reflect.DeepEqual(results, expected) {
+		t.Fatalf("createOrdersByFI returned %s, expecting %+v", bytes, expected)
+	}
+	if fiOrder, _ := getFIOrder(s, "10002"); fiOrder == nil || fiOrder.ClientOrderRef != "R2" || fiOrder.Quantity != 5 {
+		t.Fatalf("order 10002 is %+v, expecting the order submitted as R2", fiOrder)
+	}
+
+	counter, _ := s.GetState(FIOrderCounterKey)
+	if _, err := s.as(RoleFI, "FI1").invoke("createOrdersByFI", "FI1", "["+withRef("R3", 10)+","+withRef("R4", 0)+"]"); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("createOrdersByFI of a batch with an invalid order returned %v", err)
+	}
+	if after, _ := s.GetState(FIOrderCounterKey); string(after) != string(counter) {
+		t.Fatalf("failing batch moved the order counter from %s to %s", counter, after)
+	}
+	var response orderPage
+	bytes, err := s.as(RoleFI, "FI1").query("getAllOrdersForFIBasedOnStatus", "FI1", "")
+	if err != nil || json.Unmarshal(bytes, &response) != nil || response.Count != 2 {
+		t.Fatalf("failing batch left the orders %s %v", bytes, err)
+	}
+	if len(s.events) != 0 {
+		t.Fatalf("failing batch set %d events", len(s.events))
+	}
+}
+
+// TestCreateOrdersSubmitter test that orders are only created for the FI of the caller
+func TestCreateOrdersSubmitter(t *testing.T) {
+	s := newTestStub()
+	order := limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC)
+	forFI2 := strings.Replace(order, "{", `{"fiID":"FI2",`, 1)
+
+	for _, c := range []struct {
+		role   Role
+		caller string
+		FIID   string
+		orders string
+	}{
+		{RoleFI, "FI1", "FI1", "[" + order + "," + forFI2 + "]"},
+		{RoleFI, "FI1", "FI2", "[" + forFI2 + "]"},
+		{RoleBroker, "FI1", "FI1", "[" + order + "]"},
+	} {
+		if _, err := s.as(c.role, c.caller).invoke("createOrdersByFI", c.FIID, c.orders); ccerror.CodeOf(err) != ccerror.Unauthorized {
+			t.Fatalf("createOrdersByFI(%s, %s) as %s %s returned %v", c.FIID, c.orders, c.role, c.caller, err)
+		}
+	}
+	if fiOrder, _ := getFIOrder(s, "10001"); fiOrder != nil {
+		t.Fatalf("rejected batches created %+v", fiOrder)
+	}
+
+	s.as(RoleFI, "FI1").mustInvoke(t, "createOrdersByFI", "FI1", "["+order+"]")
+	if fiOrder, _ := getFIOrder(s, "10001"); fiOrder == nil || fiOrder.FIID != "FI1" {
+		t.Fatalf("order created without a fiID is %+v, expecting it for FI1", fiOrder)
+	}
+}
+
+// TestPartyAccess test that brokers and custodians only act on the orders routed to them
+func TestPartyAccess(t *testing.T) {
+	s := newTestStub()
+	acknowledged := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC))
+	executed := s.executedOrder(t, OrderSideBuy, 10, "100")
+	s.as(RoleFI, "FI1").mustInvoke(t, "sendAllocationInstruction", "FI1", executed, `[{"accountID":"A1","quantity":10}]`)
+
+	for _, c := range []struct {
+		role     Role
+		caller   string
+		function string
+		args     []string
+	}{
+		{RoleBroker, "B2", "confirmOrder", []string{"B2", acknowledged, `{"executedPrice":100,"executedQuantity":10,"exchangeTradeNumber":"X1"}`}},
+		{RoleBroker, "B2", "rejectOrder", []string{"B2", acknowledged, "no reason"}},
+		{RoleBroker, "B2", "submitOrderToBook", []string{"B2", acknowledged}},
+		{RoleBroker, "B2", "confirmOrder", []string{"B1", acknowledged, `{"executedPrice":100,"executedQuantity":10,"exchangeTradeNumber":"X1"}`}},
+		{RoleCustodian, "C2", "affirmAllocation", []string{"C2", executed}},
+		{RoleCustodian, "C2", "affirmAllocation", []string{"C1", executed}},
+		{RoleBroker, "B1", "affirmAllocation", []string{"C1", executed}},
+	} {
+		if _, err := s.as(c.role, c.caller).invoke(c.function, c.args...); ccerror.CodeOf(err) != ccerror.Unauthorized {
+			t.Fatalf("%s%v as %s %s returned %v", c.function, c.args, c.role, c.caller, err)
+		}
+	}
+	for _, c := range []struct {
+		role     Role
+		caller   string
+		function string
+		args     []string
+	}{
+		{RoleBroker, "B2", "getAllOrdersForBrokerBasedOnStatus", []string{"B1", ""}},
+		{RoleCustodian, "C2", "getAllOrdersForCustodianBasedOnStatus", []string{"C1", ""}},
+		{RoleBroker, "B2", "getExecutionsForOrder", []string{acknowledged}},
+		{RoleCustodian, "C2", "searchOrders", []string{`{"custodianBankID":"C1"}`}},
+	} {
+		if _, err := s.as(c.role, c.caller).query(c.function, c.args...); ccerror.CodeOf(err) != ccerror.Unauthorized {
+			t.Fatalf("%s%v as %s %s returned %v", c.function, c.args, c.role, c.caller, err)
+		}
+	}
+
+	s.attributes = nil
+	if _, err := s.invoke("expireOrders"); ccerror.CodeOf(err) != ccerror.Unauthorized {
+		t.Fatalf("expireOrders by a caller without a role returned %v", err)
+	}
+	s.as(RoleCustodian, "C2").mustInvoke(t, "expireOrders")
+}
+
+// TestSettlementTrade test the settlement of a trade grouping two orders
+func TestSettlementTrade(t *testing.T) {
+	s := newTestStub()
+	first := s.executedOrder(t, OrderSideBuy, 10, "100")
+	second := s.executedOrder(t, OrderSideBuy, 5, "100")
+	s.affirmedOrder(t, first, `[{"accountID":"A1","quantity":10}]`)
+	s.affirmedOrder(t, second, `[{"accountID":"A1","quantity":5}]`)
+	trades := func(custodianBankID string, status TradeStatus) ([]TradeObject, string) {
+		var response struct {
+			Data      []TradeObject `json:"data"`
+			ErrorCode string        `json:"errorCode"`
+		}
+		bytes, err := s.as(RoleCustodian, custodianBankID).query("getAllTradesForCustodianBasedOnStatus", custodianBankID, string(status))
+		if err != nil || json.Unmarshal(bytes, &response) != nil {
+			t.Fatalf("getAllTradesForCustodianBasedOnStatus returned %s %v", bytes, err)
+		}
+		return response.Data, response.ErrorCode
+	}
+
+	request := `{"oderTradeNumber":"T1","fiOrderIDs":["` + first + `","` + second + `"]}`
+	if _, err := s.as(RoleCustodian, "C2").invoke("createSettlementTrade", "C2", request); ccerror.CodeOf(err) != ccerror.Unauthorized {
+		t.Fatalf("createSettlementTrade of orders held with another custodian returned %v", err)
+	}
+	tradeObjectID := s.settlementTrade(t, first, second)
+	if _, err := s.as(RoleCustodian, "C1").invoke("createSettlementTrade", "C1", request); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("createSettlementTrade of orders already in a trade returned %v", err)
+	}
+	for _, fiOrderID := range []string{first, second} {
+		if fiOrder, _ := getFIOrder(s, fiOrderID); fiOrder.Status != OrderStatusAllocated || fiOrder.TradeObjectID != tradeObjectID {
+			t.Fatalf("order %s is %s in trade %s, expecting it Allocated in trade %s", fiOrderID, fiOrder.Status, fiOrder.TradeObjectID, tradeObjectID)
+		}
+	}
+	if pending, code := trades("C1", TradeStatusPending); len(pending) != 1 || code != QueryCodeOK ||
+		!reflect.DeepEqual(pending[0].FIOrderIDs, []string{first, second}) {
+		t.Fatalf("pending trades of C1 are %+v %s", pending, code)
+	}
+	if _, code := trades("C1", TradeStatusSettled); code != QueryCodeNoMatch {
+		t.Fatalf("settled trades of C1 returned %s, expecting %s", code, QueryCodeNoMatch)
+	}
+	if _, code := trades("C2", ""); code != QueryCodeNotFound {
+		t.Fatalf("trades of C2 returned %s, expecting %s", code, QueryCodeNotFound)
+	}
+
+	if _, err := s.as(RoleCustodian, "C1").invoke("settleTrade", "C1", tradeObjectID, "tomorrow"); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("settleTrade with an invalid date returned %v", err)
+	}
+	if _, err := s.as(RoleCustodian, "C2").invoke("settleTrade", "C2", tradeObjectID, "1500000000000"); ccerror.CodeOf(err) != ccerror.Unauthorized {
+		t.Fatalf("settleTrade by another custodian returned %v", err)
+	}
+	s.as(RoleCustodian, "C1").mustInvoke(t, "settleTrade", "C1", tradeObjectID, "1500000000000")
+	if _, err := s.as(RoleCustodian, "C1").invoke("settleTrade", "C1", tradeObjectID, "1500000000000"); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("settleTrade of a settled trade returned %v", err)
+	}
+	for _, fiOrderID := range []string{first, second} {
+		if fiOrder, _ := getFIOrder(s, fiOrderID); fiOrder.Status != OrderStatusSettled {
+			t.Fatalf("order %s is %s, expecting it Settled", fiOrderID, fiOrder.Status)
+		}
+	}
+	settled, code := trades("C1", TradeStatusSettled)
+	if len(settled) != 1 || code != QueryCodeOK || !settled[0].SettlementDate.Equal(time.Unix(1500000000, 0)) {
+		t.Fatalf("settled trades of C1 are %+v %s", settled, code)
+	}
+	if _, code = trades("C1", TradeStatusPending); code != QueryCodeNoMatch {
+		t.Fatalf("pending trades of C1 returned %s, expecting %s", code, QueryCodeNoMatch)
+	}
+}
+
+// TestHoldingsAndTransactions test the bookings of settled buy and sell orders
+func TestHoldingsAndTransactions(t *testing.T) {
+	s := newTestStub()
+	const firstDay, secondDay = "1500000000000", "1500086400000"
+	buy := s.executedOrder(t, OrderSideBuy, 10, "100")
+	s.affirmedOrder(t, buy, `[{"accountID":"A1","quantity":6},{"accountID":"A2","quantity":4}]`)
+	s.as(RoleCustodian, "C1").mustInvoke(t, "settleTrade", "C1", s.settlementTrade(t, buy), firstDay)
+	sell := s.executedOrder(t, OrderSideSell, 3, "101")
+	s.affirmedOrder(t, sell, `[{"accountID":"A1","quantity":3}]`)
+	s.as(RoleCustodian, "C1").mustInvoke(t, "settleTrade", "C1", s.settlementTrade(t, sell), secondDay)
+
+	var holdings struct {
+		Data []Holding `json:"data"`
+	}
+	bytes, err := s.as(RoleFI, "FI1").query("getHoldingsForFI", "FI1", "IBM")
+	if err != nil || json.Unmarshal(bytes, &holdings) != nil {
+		t.Fatalf("getHoldingsForFI returned %s %v", bytes, err)
+	}
+	expectedHoldings := []Holding{
+		{FIID: "FI1", AccountID: "A1", StockID: "IBM", Balance: 3},
+		{FIID: "FI1", AccountID: "A2", StockID: "IBM", Balance: 4},
+	}
+	if !reflect.DeepEqual(holdings.Data, expectedHoldings) {
+		t.Fatalf("holdings are %+v, expecting %+v", holdings.Data, expectedHoldings)
+	}
+
+	transactions := func(fromDate string, toDate string) ([]Transaction, string) {
+		var response struct {
+			Data      []Transaction `json:"data"`
+			ErrorCode string        `json:"errorCode"`
+		}
+		bytes, err := s.as(RoleFI, "FI1").query("getTransactionsForFI", "FI1", "IBM", fromDate, toDate)
+		if err != nil || json.Unmarshal(bytes, &response) != nil {
+			t.Fatalf("getTransactionsForFI returned %s %v", bytes, err)
+		}
+		return response.Data, response.ErrorCode
+	}
+	found, _ := transactions(firstDay, secondDay)
+	if len(found) != 3 {
+		t.Fatalf("getTransactionsForFI found %+v, expecting 3 transactions", found)
+	}
+	for i, expected := range []struct {
+		accountID       string
+		transactionType string
+		quantity        int
+		balance         int
+		fiOrderID       string
+	}{
+		{"A1", TransactionTypeCredit, 6, 6, buy},
+		{"A2", TransactionTypeCredit, 4, 4, buy},
+		{"A1", TransactionTypeDebit, 3, 3, sell},
+	} {
+		transaction := found[i]
+		if transaction.AccountID != expected.accountID || transaction.TransactionType != expected.transactionType ||
+			transaction.Quanity != expected.quantity || transaction.EffectiveBalance != expected.balance || transaction.FIOrderID != expected.fiOrderID {
+			t.Fatalf("transaction %d is %+v, expecting %+v", i, transaction, expected)
+		}
+	}
+
+	// both ends of the range are inclusive
+	if found, _ = transactions(secondDay, secondDay); len(found) != 1 || found[0].FIOrderID != sell {
+		t.Fatalf("transactions of the second day are %+v", found)
+	}
+	if found, _ = transactions(firstDay, firstDay); len(found) != 2 || found[0].FIOrderID != buy {
+		t.Fatalf("transactions of the first day are %+v", found)
+	}
+	if found, code := transactions("1500000000001", "1500086399999"); len(found) != 0 || code != QueryCodeNoMatch {
+		t.Fatalf("transactions between the two days are %+v %s", found, code)
+	}
+}
+
+// TestLifecycleEvents test the event set by each step of the lifecycle of an order
+func TestLifecycleEvents(t *testing.T) {
+	s := newTestStub()
+	buy := limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC)
+
+	s.as(RoleFI, "FI1").mustInvoke(t, "createOrdersByFI", "FI1", "["+buy+","+buy+"]")
+	event := s.lifecycleEvent(t, EventOrdersCreated)
+	expected := []OrderEvent{
+		{FIOrderID: "10001", FIID: "FI1", BrokerID: "B1", CustodianBankID: "C1", StockID: "IBM", Status: OrderStatusNew},
+		{FIOrderID: "10002", FIID: "FI1", BrokerID: "B1", CustodianBankID: "C1", StockID: "IBM", Status: OrderStatusNew},
+	}
+	if !reflect.DeepEqual(event.Orders, expected) || event.Trade != nil || event.Executions != nil {
+		t.Fatalf("OrdersCreated event is %+v, expecting orders %+v", event, expected)
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(s.events[0].payload, &payload); err != nil || len(payload) != 3 ||
+		payload["type"] == nil || payload["timestamp"] == nil || payload["orders"] == nil {
+		t.Fatalf("OrdersCreated payload is %s, expecting type, timestamp and orders", s.events[0].payload)
+	}
+
+	s.as(RoleBroker, "B1").mustInvoke(t, "acknowledgeOrder", "B1", "10001")
+	event = s.lifecycleEvent(t, EventOrderStatusChanged)
+	if len(event.Orders) != 1 || event.Orders[0].FIOrderID != "10001" ||
+		event.Orders[0].PreviousStatus != OrderStatusNew || event.Orders[0].Status != OrderStatusAcknowledged {
+		t.Fatalf("OrderStatusChanged event is %+v", event)
+	}
+
+	s.as(RoleBroker, "B1").mustInvoke(t, "confirmOrder", "B1", "10001",
+		`{"executedPrice":100,"executedQuantity":10,"exchangeTradeNumber":"X1"}`)
+	s.affirmedOrder(t, "10001", `[{"accountID":"A1","quantity":10}]`)
+	tradeObjectID := s.settlementTrade(t, "10001")
+	event = s.lifecycleEvent(t, EventTradeCreated)
+	if event.Trade == nil || event.Trade.TradeObjectID != tradeObjectID || len(event.Orders) != 1 ||
+		event.Orders[0].PreviousStatus != OrderStatusExecuted || event.Orders[0].Status != OrderStatusAllocated {
+		t.Fatalf("TradeCreated event is %+v", event)
+	}
+
+	s.as(RoleCustodian, "C1").mustInvoke(t, "settleTrade", "C1", tradeObjectID, "1500000000000")
+	event = s.lifecycleEvent(t, EventTradeSettled)
+	if event.Trade == nil || event.Trade.TradeObjectID != tradeObjectID || len(event.Orders) != 1 ||
+		event.Orders[0].PreviousStatus != OrderStatusAllocated || event.Orders[0].Status != OrderStatusSettled {
+		t.Fatalf("TradeSettled event is %+v", event)
+	}
+
+	// a failed transaction sets no event
+	if _, err := s.as(RoleCustodian, "C1").invoke("settleTrade", "C1", tradeObjectID, "1500000000000"); err == nil || len(s.events) != 0 {
+		t.Fatalf("settling a settled trade returned %v and set %d events", err, len(s.events))
+	}
+}
+
+// TestAllocationGate test that an order settles only with affirmed allocations of its executed quantity
+func TestAllocationGate(t *testing.T) {
+	s := newTestStub()
+	fiOrderID := s.executedOrder(t, OrderSideBuy, 10, "100")
+	request := `{"fiOrderIDs":["` + fiOrderID + `"]}`
+
+	if _, err := s.as(RoleCustodian, "C1").invoke("createSettlementTrade", "C1", request); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("createSettlementTrade without allocations returned %v", err)
+	}
+	if _, err := s.as(RoleCustodian, "C1").invoke("affirmAllocation", "C1", fiOrderID); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("affirmAllocation before any instruction returned %v", err)
+	}
+	acknowledged := s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "100", OrderValidityGTC))
+	if _, err := s.as(RoleFI, "FI1").invoke("sendAllocationInstruction", "FI1", acknowledged, `[{"accountID":"A1","quantity":10}]`); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("sendAllocationInstruction of an order not executed returned %v", err)
+	}
+	if _, err := s.as(RoleFI, "FI1").invoke("sendAllocationInstruction", "FI1", fiOrderID, `[{"accountID":"A1","quantity":9}]`); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("sendAllocationInstruction of 9 out of 10 returned %v", err)
+	}
+	s.as(RoleFI, "FI1").mustInvoke(t, "sendAllocationInstruction", "FI1", fiOrderID, `[{"accountID":"A1","quantity":6},{"accountID":"A2","quantity":4}]`)
+	if _, err := s.as(RoleCustodian, "C1").invoke("createSettlementTrade", "C1", request); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("createSettlementTrade with pending allocations returned %v", err)
+	}
+	s.as(RoleCustodian, "C1").mustInvoke(t, "affirmAllocation", "C1", fiOrderID)
+	if _, err := s.as(RoleFI, "FI1").invoke("sendAllocationInstruction", "FI1", fiOrderID, `[{"accountID":"A1","quantity":10}]`); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("sendAllocationInstruction after affirmation returned %v", err)
+	}
+
+	// allocations written to the ledger behind the chaincode's back are checked again
+	previous, _ := getFIOrder(s, fiOrderID)
+	forged := *previous
+	forged.AllocationInstruction = &AllocationInstruction{Status: AllocationStatusAffirmed, Allocations: []Allocation{{AccountID: "A1", Quantity: 1000000}}}
+	if err := putFIOrder(s, forged, previous); err != nil {
+		t.Fatalf("putFIOrder returned %v", err)
+	}
+	if _, err := s.as(RoleCustodian, "C1").invoke("createSettlementTrade", "C1", request); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("createSettlementTrade with forged allocations returned %v", err)
+	}
+	if err := putFIOrder(s, *previous, &forged); err != nil {
+		t.Fatalf("putFIOrder returned %v", err)
+	}
+	tradeObjectID := s.settlementTrade(t, fiOrderID)
+
+	previous, _ = getFIOrder(s, fiOrderID)
+	forged = *previous
+	forged.AllocationInstruction = &AllocationInstruction{Status: AllocationStatusAffirmed, Allocations: []Allocation{{AccountID: "A1", Quantity: 1000000}}}
+	if err := putFIOrder(s, forged, previous); err != nil {
+		t.Fatalf("putFIOrder returned %v", err)
+	}
+	if _, err := s.as(RoleCustodian, "C1").invoke("settleTrade", "C1", tradeObjectID, "1500000000000"); ccerror.CodeOf(err) != ccerror.InvalidState {
+		t.Fatalf("settleTrade with forged allocations returned %v", err)
+	}
+}
+
+// orderPage is the QueryResponse of searchOrders
+type orderPage struct {
+	Data      []FIOrder `json:"data"`
+	Count     int       `json:"count"`
+	Bookmark  string    `json:"bookmark"`
+	ErrorCode string    `json:"errorCode"`
+}
+
+// TestSearchOrders test the paging of searchOrders
+func TestSearchOrders(t *testing.T) {
+	s := newTestStub()
+	var orders []string
+	for i := 0; i < 5; i++ {
+		orders = append(orders, limitOrder(OrderSideBuy, 1+i, "10", OrderValidityGTC))
+		s.now = s.now.Add(time.Minute)
+		if _, err := s.as(RoleFI, "FI1").invoke("createOrdersByFI", "FI1", "["+orders[i]+"]"); err != nil {
+			t.Fatalf("createOrdersByFI returned %v", err)
+		}
+	}
+
+	for _, sortOrder := range []string{SortAscending, SortDescending} {
+		var ids []string
+		bookmark := ""
+		for page := 0; page == 0 || len(bookmark) > 0; page++ {
+			var response orderPage
+			filter := `{"fiID":"FI1","pageSize":2,"sortOrder":"` + sortOrder + `","bookmark":"` + bookmark + `"}`
+			bytes, err := s.as(RoleFI, "FI1").query("searchOrders", filter)
+			if err != nil || json.Unmarshal(bytes, &response) != nil || response.Count != len(response.Data) {
+				t.Fatalf("searchOrders returned %s %v", bytes, err)
+			}
+			if page > 2 || (page < 2 && response.Count != 2) || (page == 2 && response.Count != 1) {
+				t.Fatalf("page %d of searchOrders returned %s", page, bytes)
+			}
+			for _, fiOrder := range response.Data {
+				ids = append(ids, fiOrder.FIOrderID)
+			}
+			bookmark = response.Bookmark
+		}
+		expected := []string{"10001", "10002", "10003", "10004", "10005"}
+		if sortOrder == SortDescending {
+			expected = []string{"10005", "10004", "10003", "10002", "10001"}
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Fatalf("searchOrders %s returned %v, expecting %v", sortOrder, ids, expected)
+		}
+	}
+
+	// orders are created a minute apart from 10:01, the range is inclusive at both ends
+	ms := func(minute int) string {
+		return strconv.FormatInt(time.Date(2017, 7, 14, 10, minute, 0, 0, time.UTC).UnixNano()/int64(time.Millisecond), 10)
+	}
+	for _, sortOrder := range []string{SortAscending, SortDescending} {
+		var response orderPage
+		filter := `{"fiID":"FI1","fromDate":"` + ms(2) + `","toDate":"` + ms(4) + `","sortOrder":"` + sortOrder + `"}`
+		bytes, err := s.as(RoleFI, "FI1").query("searchOrders", filter)
+		if err != nil || json.Unmarshal(bytes, &response) != nil || response.Count != 3 || len(response.Bookmark) > 0 {
+			t.Fatalf("searchOrders %s between 10:02 and 10:04 returned %s %v", sortOrder, bytes, err)
+		}
+		if first := response.Data[0].FIOrderID; (sortOrder == SortAscending && first != "10002") || (sortOrder == SortDescending && first != "10004") {
+			t.Fatalf("searchOrders %s between 10:02 and 10:04 started at %s", sortOrder, first)
+		}
+	}
+
+	if _, err := s.as(RoleFI, "FI1").query("searchOrders", `{"fiID":"FI1","bookmark":"%%"}`); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("searchOrders with an invalid bookmark returned %v", err)
+	}
+	if _, err := s.as(RoleFI, "FI1").query("searchOrders", `{"fiID":"FI2"}`); ccerror.CodeOf(err) != ccerror.Unauthorized {
+		t.Fatalf("searchOrders for another FI returned %v", err)
+	}
+}
+
+// TestQueryResponse test the error codes of the query envelope
+func TestQueryResponse(t *testing.T) {
+	s := newTestStub()
+	s.acknowledgedOrder(t, limitOrder(OrderSideBuy, 10, "10", OrderValidityGTC))
+
+	for _, c := range []struct {
+		FIID     string
+		status   string
+		response string
+	}{
+		{"FI2", "", `{"data":[],"count":0,"errorCode":"NOT_FOUND"}`},
+		{"FI1", string(OrderStatusSettled), `{"data":[],"count":0,"errorCode":"NO_MATCH"}`},
+	} {
+		bytes, err := s.as(RoleFI, c.FIID).query("getAllOrdersForFIBasedOnStatus", c.FIID, c.status)
+		if err != nil || string(bytes) != c.response {
+			t.Fatalf("getAllOrdersForFIBasedOnStatus(%s, %s) returned %s %v", c.FIID, c.status, bytes, err)
+		}
+	}
+
+	var response orderPage
+	bytes, err := s.as(RoleFI, "FI1").query("getAllOrdersForFIBasedOnStatus", "FI1", string(OrderStatusAcknowledged))
+	if err != nil || json.Unmarshal(bytes, &response) != nil || response.ErrorCode != QueryCodeOK || response.Count != 1 || len(response.Data) != 1 {
+		t.Fatalf("getAllOrdersForFIBasedOnStatus returned %s %v", bytes, err)
+	}
+}
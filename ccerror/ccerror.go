@@ -0,0 +1,78 @@
+// Package ccerror holds the error type returned by the chaincodes of this repository.
+// Its Error method serializes the error as JSON, so that a client calling a chaincode
+// through the REST API can tell the kind of failure from its code instead of parsing messages.
+package ccerror
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Code is the stable, machine-readable kind of an Error
+type Code string
+
+// Error codes
+const (
+	BadArgs      Code = "bad-args"      // the function or its arguments are invalid
+	NotFound     Code = "not-found"     // a record the function needs does not exist
+	Unauthorized Code = "unauthorized"  // the caller is not allowed to call the function or act on the record
+	InvalidState Code = "invalid-state" // the record is not in a state the function can act on
+	Conflict     Code = "conflict"      // the function would create a record that already exists
+	Internal     Code = "internal"      // reading or writing the ledger failed
+)
+
+// Error is an error with a Code. Details carries data about the error, for instance
+// every invalid field of a record.
+type Error struct {
+	Code    Code        `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// New returns an Error with code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf returns an Error with code and a message formatted as fmt.Sprintf does
+func Errorf(code Code, format string, a ...interface{}) *Error {
+	return New(code, fmt.Sprintf(format, a...))
+}
+
+// WithDetails sets the Details of e and returns it
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// Error returns e as JSON, {"code":"...","message":"..."}
+func (e *Error) Error() string {
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"code":%q,"message":%q}`, e.Code, e.Message)
+	}
+	return string(bytes)
+}
+
+// Wrap returns err as an *Error, with the Internal code when it is not one already.
+// It returns nil when err is nil.
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return New(Internal, err.Error())
+}
+
+// CodeOf returns the Code of err, Internal when err is not an *Error and an empty Code when err is nil
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return Internal
+}
@@ -0,0 +1,27 @@
+package ccerror
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestError test the JSON form and the codes of errors
+func TestError(t *testing.T) {
+	err := New(NotFound, "Unable to find order 10001")
+	if err.Error() != `{"code":"not-found","message":"Unable to find order 10001"}` {
+		t.Fatalf("Unexpected JSON %s", err.Error())
+	}
+	details := New(BadArgs, "Invalid orders").WithDetails([]string{"quantity"})
+	if details.Error() != `{"code":"bad-args","message":"Invalid orders","details":["quantity"]}` {
+		t.Fatalf("Unexpected JSON %s", details.Error())
+	}
+	if Wrap(nil) != nil || CodeOf(nil) != "" {
+		t.Fatalf("nil error should stay nil")
+	}
+	if CodeOf(Wrap(err)) != NotFound {
+		t.Fatalf("Wrap should keep the code of an Error")
+	}
+	if CodeOf(Wrap(errors.New("ledger down"))) != Internal {
+		t.Fatalf("Wrap should report other errors as internal")
+	}
+}
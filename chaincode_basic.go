@@ -2,10 +2,15 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+	"github.com/ruchika05/learn-chaincode/router"
 )
 
 // Object details
@@ -19,6 +24,9 @@ type Object struct {
 // ListOfObjects to store all objects
 type ListOfObjects map[string]string
 
+// ListOfObjectsKey is the key the ListOfObjects is stored under ==> ListOfObjects[ID] = Name
+const ListOfObjectsKey = "ListOfObjects"
+
 // MyChaincode function
 type MyChaincode struct {
 }
@@ -28,7 +36,7 @@ func getListOfObjects(shim shim.ChaincodeStubInterface) (map[string]string, erro
 	var bytesRead []byte
 	var list map[string]string
 
-	bytesRead, err = shim.GetState("ListOfObjects")
+	bytesRead, err = shim.GetState(ListOfObjectsKey)
 	if err != nil {
 		fmt.Println("Unable to get the list of Objects")
 		return nil, err
@@ -41,17 +49,8 @@ func getListOfObjects(shim shim.ChaincodeStubInterface) (map[string]string, erro
 			return nil, err
 		}
 	} else {
+		// the list is only written by setListOfObjects, so that queries can read it too
 		list = make(map[string]string)
-		bytesRead, err = json.Marshal(&list)
-		if err != nil {
-			fmt.Println("Unable to get the list of Objects")
-			return nil, err
-		}
-		err = shim.PutState("ListOfObjects", bytesRead)
-		if err != nil {
-			fmt.Println("Unable to get the list of Objects")
-			return nil, err
-		}
 	}
 	fmt.Println("returning the list of objects")
 	return list, nil
@@ -67,7 +66,7 @@ func setListOfObjects(shim shim.ChaincodeStubInterface, list map[string]string)
 		fmt.Println("Unable to update the list of Objects")
 		return err
 	}
-	err = shim.PutState("ListofObjects", bytesRead)
+	err = shim.PutState(ListOfObjectsKey, bytesRead)
 	if err != nil {
 		fmt.Println("Unable to update the list of Objects")
 		return err
@@ -77,89 +76,522 @@ func setListOfObjects(shim shim.ChaincodeStubInterface, list map[string]string)
 
 }
 
-func addObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	var err error
-	//var bytesRead []byte
+// decodes the object value into obj, rejecting fields Object does not have
+func decodeObject(value string, obj *Object) error {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(obj)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return ccerror.New(ccerror.BadArgs, "Invalid object: "+err.Error())
+	}
+	return nil
+}
 
+// tells whether id is the key of a record of the chaincode other than an object
+func isReservedID(id string) bool {
+	return id == ListOfObjectsKey || strings.HasPrefix(id, StockMovementsKeyPrefix)
+}
+
+// checks the fields of obj, and that its id does not collide with the other keys of the chaincode
+func validateObject(obj Object) error {
+	switch {
+	case len(obj.ID) == 0:
+		return ccerror.New(ccerror.BadArgs, "Object has no id")
+	case isReservedID(obj.ID):
+		return ccerror.New(ccerror.BadArgs, "Object id "+obj.ID+" is reserved")
+	case len(obj.Name) == 0:
+		return ccerror.New(ccerror.BadArgs, "Object "+obj.ID+" has no name")
+	case obj.Quantity < 0:
+		return ccerror.Errorf(ccerror.BadArgs, "Object %s has a negative quantity %d", obj.ID, obj.Quantity)
+	case obj.Price < 0:
+		return ccerror.Errorf(ccerror.BadArgs, "Object %s has a negative price %v", obj.ID, obj.Price)
+	}
+	return nil
+}
+
+// stores the object args[0] under its id. When createOnly is set, an object already stored
+// under that id is a conflict, otherwise it is replaced.
+func putObject(stub shim.ChaincodeStubInterface, args []string, createOnly bool) ([]byte, error) {
 	var obj Object
 
-	if len(args) != 1 {
-		fmt.Println("addObject called with incorrect number of arguments")
-		return nil, errors.New("addObject called with incorrect number of arguments")
+	err := decodeObject(args[0], &obj)
+	if err != nil {
+		return nil, err
 	}
+	err = validateObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if createOnly {
+		stored, err := getStoredObject(stub, obj.ID)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			return nil, ccerror.New(ccerror.Conflict, "Object "+obj.ID+" already exists")
+		}
+	}
+
+	bytesRead, err := json.Marshal(&obj)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	err = stub.PutState(obj.ID, bytesRead)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+	err = registerObject(stub, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("stored obj : %v\n", obj)
+	return bytesRead, nil
+}
+
+// addObject stores the object args[0] under its id, replacing any object stored there
+func addObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	fmt.Printf("addObject called with args : %v\n", args[0])
+	return putObject(stub, args, false)
+}
+
+// createObject stores the object args[0] under its id, which must not be in use
+func createObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("createObject called with args : %v\n", args[0])
+	return putObject(stub, args, true)
+}
 
-	err = json.Unmarshal([]byte(args[0]), &obj)
+// ObjectTombstone is returned by removeObject in place of the removed object
+type ObjectTombstone struct {
+	ID      string `json:"id"`
+	Removed bool   `json:"removed"`
+	Object  Object `json:"object"` // object as it was before its removal
+}
 
+// adds obj to the ListOfObjects, or updates its name there
+func registerObject(stub shim.ChaincodeStubInterface, obj Object) error {
+	list, err := getListOfObjects(stub)
+	if err != nil {
+		return ccerror.Wrap(err)
+	}
+	if name, ok := list[obj.ID]; ok && name == obj.Name {
+		return nil
+	}
+	list[obj.ID] = obj.Name
+	return ccerror.Wrap(setListOfObjects(stub, list))
+}
+
+// returns the object stored under id, nil if there is none. Reserved ids are refused.
+func getStoredObject(stub shim.ChaincodeStubInterface, id string) (*Object, error) {
+	var obj Object
+
+	if isReservedID(id) {
+		return nil, ccerror.New(ccerror.BadArgs, "Object id "+id+" is reserved")
+	}
+	bytesRead, err := stub.GetState(id)
 	if err != nil {
 		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
 	}
+	if len(bytesRead) == 0 {
+		return nil, nil
+	}
+	err = json.Unmarshal(bytesRead, &obj)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.New(ccerror.Internal, "Object "+id+" is corrupt")
+	}
+	return &obj, nil
+}
 
-	err = stub.PutState(obj.ID, []byte(args[0]))
+func removeObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("removeObject called with args : %v\n", args[0])
 
+	obj, err := getStoredObject(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find object "+args[0])
+	}
+
+	err = stub.DelState(args[0])
 	if err != nil {
 		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+	list, err := getListOfObjects(stub)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	delete(list, args[0])
+	err = setListOfObjects(stub, list)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
 	}
 
-	fmt.Printf("addObject called with obj : %v\n", obj)
+	fmt.Printf("removeObject removed obj : %v\n", *obj)
+	return json.Marshal(&ObjectTombstone{ID: obj.ID, Removed: true, Object: *obj})
 
-	return nil, nil
+}
+
+// updateObject merges the fields given in args[0] into the object stored under its id
+func updateObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	var update Object
+
+	fmt.Printf("updateObject called with args : %v\n", args[0])
+
+	err := decodeObject(args[0], &update)
+	if err != nil {
+		return nil, err
+	}
+	if len(update.ID) == 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "updateObject called without an id")
+	}
+	obj, err := getStoredObject(stub, update.ID)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find object "+update.ID)
+	}
+
+	// unmarshalling over the stored object only replaces the fields present in args[0]
+	err = decodeObject(args[0], obj)
+	if err != nil {
+		return nil, err
+	}
+	err = validateObject(*obj)
+	if err != nil {
+		return nil, err
+	}
+	bytesRead, err := json.Marshal(obj)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	err = stub.PutState(obj.ID, bytesRead)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+	err = registerObject(stub, *obj)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("updateObject updated obj : %v\n", *obj)
+	return bytesRead, nil
 
 }
-func removeObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 1 {
-		fmt.Println("removeObject called with incorrect number of arguments")
-		return nil, errors.New("removeObject called with incorrect number of arguments")
+
+// StockMovementsKeyPrefix prefixes the key the movements of an object are stored under ==> StockMovements~ID
+const StockMovementsKeyPrefix = "StockMovements~"
+
+// UserAttribute is the certificate attribute naming the caller of a stock movement
+const UserAttribute = "username"
+
+// Kinds of stock movements
+const (
+	StockReceipt    = "receipt"
+	StockIssue      = "issue"
+	StockAdjustment = "adjustment"
+)
+
+// StockMovement records a change of the Quantity of an object
+type StockMovement struct {
+	ObjectID string    `json:"id"`
+	Type     string    `json:"type"`
+	Delta    int       `json:"delta"`
+	Quantity int       `json:"qty"` // quantity of the object after the movement
+	Reason   string    `json:"reason"`
+	User     string    `json:"user"`
+	Date     time.Time `json:"date"`
+}
+
+// returns the movements recorded for id, oldest first
+func getStockMovements(stub shim.ChaincodeStubInterface, id string) ([]StockMovement, error) {
+	movements := []StockMovement{}
+
+	bytesRead, err := stub.GetState(StockMovementsKeyPrefix + id)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
 	}
-	fmt.Printf("removeObject called with args : %v\n", args[0])
-	return nil, nil
+	if len(bytesRead) == 0 {
+		return movements, nil
+	}
+	err = json.Unmarshal(bytesRead, &movements)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.New(ccerror.Internal, "Stock movements of object "+id+" are corrupt")
+	}
+	return movements, nil
+}
+
+// changes the Quantity of the object id by delta and records the movement.
+// The quantity may not become negative.
+func moveStock(stub shim.ChaincodeStubInterface, movementType string, id string, delta int, reason string) ([]byte, error) {
+	user, err := stub.ReadCertAttribute(UserAttribute)
+	if err != nil || len(user) == 0 {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.New(ccerror.Unauthorized, "Caller certificate has no "+UserAttribute+" attribute")
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	if txTimestamp == nil {
+		return nil, ccerror.New(ccerror.Internal, "Transaction has no timestamp")
+	}
+
+	obj, err := getStoredObject(stub, id)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, ccerror.New(ccerror.NotFound, "Unable to find object "+id)
+	}
+	if obj.Quantity+delta < 0 {
+		return nil, ccerror.Errorf(ccerror.InvalidState, "Object %s has a quantity of %d, unable to apply a %s of %d", id, obj.Quantity, movementType, delta)
+	}
+	obj.Quantity += delta
 
+	movements, err := getStockMovements(stub, id)
+	if err != nil {
+		return nil, err
+	}
+	movements = append(movements, StockMovement{
+		ObjectID: id,
+		Type:     movementType,
+		Delta:    delta,
+		Quantity: obj.Quantity,
+		Reason:   reason,
+		User:     string(user),
+		Date:     time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(),
+	})
+	bytesRead, err := json.Marshal(&movements)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	err = stub.PutState(StockMovementsKeyPrefix+id, bytesRead)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+
+	bytesRead, err = json.Marshal(obj)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	err = stub.PutState(id, bytesRead)
+	if err != nil {
+		fmt.Printf("err : %v\n", err)
+		return nil, ccerror.Wrap(err)
+	}
+
+	fmt.Printf("%s of %d for object %s, quantity is now %d\n", movementType, delta, id, obj.Quantity)
+	return bytesRead, nil
 }
-func updateObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 1 {
-		fmt.Println("updateObject called with incorrect number of arguments")
-		return nil, errors.New("updateObject called with incorrect number of arguments")
+
+// parses the quantity of a stock movement, which must be a whole number
+func parseStockQuantity(value string) (int, error) {
+	quantity, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, ccerror.New(ccerror.BadArgs, "Quantity "+value+" is not a whole number")
 	}
-	fmt.Printf("updateObject called with args : %v\n", args[0])
-	return nil, nil
+	return quantity, nil
+}
+
+// receiveStock adds args[1] to the Quantity of the object args[0] for the reason args[2]
+func receiveStock(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("receiveStock called with args : %v\n", args)
+
+	quantity, err := parseStockQuantity(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if quantity <= 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "Quantity received must be positive")
+	}
+	return moveStock(stub, StockReceipt, args[0], quantity, args[2])
+}
+
+// issueStock takes args[1] from the Quantity of the object args[0] for the reason args[2]
+func issueStock(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("issueStock called with args : %v\n", args)
+
+	quantity, err := parseStockQuantity(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if quantity <= 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "Quantity issued must be positive")
+	}
+	return moveStock(stub, StockIssue, args[0], -quantity, args[2])
+}
+
+// adjustStock changes the Quantity of the object args[0] by the signed delta args[1] for the reason args[2]
+func adjustStock(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("adjustStock called with args : %v\n", args)
 
+	delta, err := parseStockQuantity(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if delta == 0 {
+		return nil, ccerror.New(ccerror.BadArgs, "Adjustment must not be zero")
+	}
+	return moveStock(stub, StockAdjustment, args[0], delta, args[2])
 }
 
 func getObject(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var err error
 	var bytesRead []byte
 
-	if len(args) != 1 {
-		fmt.Println("getObject called with incorrect number of arguments")
-		return nil, errors.New("getObject called with incorrect number of arguments")
-	}
 	fmt.Printf("getObject called with args : %v\n", args[0])
 
+	if isReservedID(args[0]) {
+		return nil, ccerror.New(ccerror.BadArgs, "Object id "+args[0]+" is reserved")
+	}
 	bytesRead, err = stub.GetState(args[0])
 	if err != nil {
 		fmt.Printf("err : %v\n", err)
-		return nil, err
+		return nil, ccerror.Wrap(err)
 	}
 	return bytesRead, nil
 
 }
 
+// returns every object of the ListOfObjects, ordered by id
+func getRegisteredObjects(stub shim.ChaincodeStubInterface) ([]Object, error) {
+	list, err := getListOfObjects(stub)
+	if err != nil {
+		return nil, ccerror.Wrap(err)
+	}
+	ids := make([]string, 0, len(list))
+	for id := range list {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	objects := []Object{}
+	for _, id := range ids {
+		obj, err := getStoredObject(stub, id)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			fmt.Printf("ListOfObjects refers to missing object %s\n", id)
+			continue
+		}
+		objects = append(objects, *obj)
+	}
+	return objects, nil
+}
+
+// getAllObjects returns every object of the ListOfObjects as a JSON array, ordered by id
 func getAllObjects(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	var isOk bool
-	val, err := stub.ReadCertAttribute("position")
-	fmt.Printf("Position => %v error %v \n", string(val), err)
-	isOk, _ = stub.VerifyAttribute("position", []byte("Software Engineer"))
-	if isOk {
-		fmt.Printf("am ok")
+	fmt.Printf("getAllObjects called\n")
 
+	objects, err := getRegisteredObjects(stub)
+	if err != nil {
+		return nil, err
 	}
+	return json.Marshal(&objects)
+
+}
+
+// ObjectValuation is the value of the stock of an object
+type ObjectValuation struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Quantity int     `json:"qty"`
+	Price    float32 `json:"price"`
+	Value    float64 `json:"value"` // Quantity * Price
+}
+
+// InventoryValuation is returned by getInventoryValuation
+type InventoryValuation struct {
+	Objects  []ObjectValuation `json:"objects"` // ordered by id
+	Quantity int               `json:"qty"`     // total quantity of all objects
+	Value    float64           `json:"value"`   // total value of all objects
+}
 
-	if len(args) != 0 {
-		fmt.Println("getAllObjects called with incorrect number of arguments")
-		return nil, errors.New("getAllObjects called with incorrect number of arguments")
+// returns the value of the stock of obj. The price is widened through its decimal form,
+// so that a price of 0.1 is worth 0.1 and not 0.10000000149011612.
+func valueOf(obj Object) float64 {
+	price, _ := strconv.ParseFloat(strconv.FormatFloat(float64(obj.Price), 'f', -1, 32), 64)
+	return float64(obj.Quantity) * price
+}
+
+// getInventoryValuation returns the value of every object of the ListOfObjects and their total
+func getInventoryValuation(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("getInventoryValuation called\n")
+
+	objects, err := getRegisteredObjects(stub)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("getAllObjects called\n")
-	return nil, nil
+	valuation := InventoryValuation{Objects: []ObjectValuation{}}
+	for _, obj := range objects {
+		value := valueOf(obj)
+		valuation.Objects = append(valuation.Objects, ObjectValuation{
+			ID:       obj.ID,
+			Name:     obj.Name,
+			Quantity: obj.Quantity,
+			Price:    obj.Price,
+			Value:    value,
+		})
+		valuation.Quantity += obj.Quantity
+		valuation.Value += value
+	}
+	return json.Marshal(&valuation)
+
+}
+
+// getLowStockObjects returns the objects of the ListOfObjects with a Quantity under args[0] as a JSON array, ordered by id
+func getLowStockObjects(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("getLowStockObjects called with args : %v\n", args[0])
+
+	threshold, err := parseStockQuantity(args[0])
+	if err != nil {
+		return nil, err
+	}
+	objects, err := getRegisteredObjects(stub)
+	if err != nil {
+		return nil, err
+	}
+	lowStock := []Object{}
+	for _, obj := range objects {
+		if obj.Quantity < threshold {
+			lowStock = append(lowStock, obj)
+		}
+	}
+	return json.Marshal(&lowStock)
+
+}
+
+// getObjectStockMovements returns the stock movements of the object args[0] as a JSON array, oldest first.
+// Movements are kept when the object is removed.
+func getObjectStockMovements(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Printf("getObjectStockMovements called with args : %v\n", args[0])
+
+	movements, err := getStockMovements(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(movements) == 0 {
+		obj, err := getStoredObject(stub, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return nil, ccerror.New(ccerror.NotFound, "Unable to find object "+args[0])
+		}
+	}
+	return json.Marshal(&movements)
 
 }
 
@@ -169,30 +601,47 @@ func (t *MyChaincode) Init(stub shim.ChaincodeStubInterface, function string, ar
 	return nil, nil
 }
 
+// Returns the functions of the chaincode
+func (t *MyChaincode) routes() *router.Router {
+	return router.New().
+		Invoke("addObject", "Stores an object under its id, replacing any object stored there",
+			[]router.Arg{{Name: "object", Type: router.Object}}, addObject).
+		Invoke("createObject", "Stores a new object under its id, failing when the id is in use",
+			[]router.Arg{{Name: "object", Type: router.Object}}, createObject).
+		Invoke("removeObject", "Removes the object stored under an id and returns its tombstone",
+			router.Strings("id"), removeObject).
+		Invoke("updateObject", "Merges the fields given into the object stored under the same id",
+			[]router.Arg{{Name: "object", Type: router.Object}}, updateObject).
+		Invoke("receiveStock", "Adds a quantity to the stock of an object",
+			[]router.Arg{{Name: "id", Type: router.String}, {Name: "quantity", Type: router.Number}, {Name: "reason", Type: router.String}}, receiveStock).
+		Invoke("issueStock", "Takes a quantity from the stock of an object",
+			[]router.Arg{{Name: "id", Type: router.String}, {Name: "quantity", Type: router.Number}, {Name: "reason", Type: router.String}}, issueStock).
+		Invoke("adjustStock", "Changes the stock of an object by a signed delta",
+			[]router.Arg{{Name: "id", Type: router.String}, {Name: "delta", Type: router.Number}, {Name: "reason", Type: router.String}}, adjustStock).
+		Query("getObject", "Returns the object stored under an id",
+			router.Strings("id"), getObject).
+		Query("getAllObjects", "Returns every object stored",
+			nil, getAllObjects).
+		Query("getStockMovements", "Returns the stock movements of an object, oldest first",
+			router.Strings("id"), getObjectStockMovements).
+		Query("getInventoryValuation", "Returns the value of the stock of every object and their total",
+			nil, getInventoryValuation).
+		Query("getLowStockObjects", "Returns the objects with a quantity under a threshold",
+			[]router.Arg{{Name: "threshold", Type: router.Number}}, getLowStockObjects)
+}
+
 // Invoke function
 func (t *MyChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("Invoke called for function: " + function)
 	fmt.Printf("args: %s\n", args)
-	if function == "addObject" {
-		return addObject(stub, args)
-	} else if function == "removeObject" {
-		return removeObject(stub, args)
-	} else if function == "updateObject" {
-		return updateObject(stub, args)
-	}
-	return nil, nil
+	return t.routes().Handle(stub, router.Invoke, function, args)
 }
 
 // Query function
 func (t *MyChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("Query called for function: " + function)
 	fmt.Printf("args: %s\n", args)
-	if function == "getObject" {
-		return getObject(stub, args)
-	} else if function == "getAllObjects" {
-		return getAllObjects(stub, args)
-	}
-	return nil, nil
+	return t.routes().Handle(stub, router.Query, function, args)
 }
 
 func main() {
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
 )
 
 // TestMyChaincode test my code
@@ -20,3 +25,227 @@ func TestMyChaincode(t *testing.T) {
 	testStub.MockQuery("getObject", []string{"123"})
 	testStub.MockQuery("getAllObjects", []string{})
 }
+
+// TestUpdateRemoveObject test the update and removal of an object
+func TestUpdateRemoveObject(t *testing.T) {
+	testStub := shim.NewMockStub("mock", new(MyChaincode))
+	testStub.MockInit("t123", "init", nil)
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "1234", "name":"Pencils","qty":1000,"price":100}`})
+
+	bytes, err := testStub.MockInvoke("t123", "updateObject", []string{`{"id": "1234", "qty":900}`})
+	if err != nil || string(bytes) != `{"id":"1234","name":"Pencils","qty":900,"price":100}` {
+		t.Fatalf("updateObject returned %s %v", bytes, err)
+	}
+	if _, err = testStub.MockInvoke("t123", "updateObject", []string{`{"id": "999", "qty":1}`}); err == nil {
+		t.Fatalf("updateObject should reject an unknown id")
+	}
+
+	bytes, err = testStub.MockInvoke("t123", "removeObject", []string{"1234"})
+	if err != nil || string(bytes) != `{"id":"1234","removed":true,"object":{"id":"1234","name":"Pencils","qty":900,"price":100}}` {
+		t.Fatalf("removeObject returned %s %v", bytes, err)
+	}
+	if bytes, _ = testStub.MockQuery("getObject", []string{"1234"}); len(bytes) != 0 {
+		t.Fatalf("object still stored after removeObject : %s", bytes)
+	}
+	if _, err = testStub.MockInvoke("t123", "removeObject", []string{"1234"}); err == nil {
+		t.Fatalf("removeObject should reject an unknown id")
+	}
+
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "1", "name":"Pens","qty":10,"price":5}`})
+	for _, id := range []string{"ListOfObjects", "StockMovements~1"} {
+		if _, err = testStub.MockInvoke("t123", "removeObject", []string{id}); ccerror.CodeOf(err) != ccerror.BadArgs {
+			t.Fatalf("removeObject of the reserved id %s returned %v", id, err)
+		}
+		if _, err = testStub.MockQuery("getObject", []string{id}); ccerror.CodeOf(err) != ccerror.BadArgs {
+			t.Fatalf("getObject of the reserved id %s returned %v", id, err)
+		}
+	}
+	if bytes, _ = testStub.MockQuery("getAllObjects", []string{}); string(bytes) != `[{"id":"1","name":"Pens","qty":10,"price":5}]` {
+		t.Fatalf("getAllObjects returned %s after removing reserved ids", bytes)
+	}
+}
+
+// TestGetAllObjects test the ListOfObjects registry
+func TestGetAllObjects(t *testing.T) {
+	testStub := shim.NewMockStub("mock", new(MyChaincode))
+	testStub.MockInit("t123", "init", nil)
+
+	bytes, err := testStub.MockQuery("getAllObjects", []string{})
+	if err != nil || string(bytes) != `[]` {
+		t.Fatalf("getAllObjects returned %s %v", bytes, err)
+	}
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "2", "name":"Pens","qty":10,"price":5}`})
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "1", "name":"Pencils","qty":1000,"price":100}`})
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "3", "name":"Erasers","qty":1,"price":1}`})
+	testStub.MockInvoke("t123", "removeObject", []string{"3"})
+
+	bytes, err = testStub.MockQuery("getAllObjects", []string{})
+	if err != nil || string(bytes) != `[{"id":"1","name":"Pencils","qty":1000,"price":100},{"id":"2","name":"Pens","qty":10,"price":5}]` {
+		t.Fatalf("getAllObjects returned %s %v", bytes, err)
+	}
+}
+
+// TestStockMovements test the refused stock movements
+func TestStockMovements(t *testing.T) {
+	testStub := shim.NewMockStub("mock", new(MyChaincode))
+	testStub.MockInit("t123", "init", nil)
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "1", "name":"Pencils","qty":10,"price":1}`})
+
+	bytes, err := testStub.MockQuery("getStockMovements", []string{"1"})
+	if err != nil || string(bytes) != `[]` {
+		t.Fatalf("getStockMovements returned %s %v", bytes, err)
+	}
+	_, err = testStub.MockQuery("getStockMovements", []string{"2"})
+	if ccerror.CodeOf(err) != ccerror.NotFound {
+		t.Fatalf("getStockMovements of a missing object returned %v", err)
+	}
+	_, err = testStub.MockInvoke("t123", "issueStock", []string{"1", "-5", "sale"})
+	if ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("issueStock of a negative quantity returned %v", err)
+	}
+	_, err = testStub.MockInvoke("t123", "receiveStock", []string{"1", "2.5", "delivery"})
+	if ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("receiveStock of a fractional quantity returned %v", err)
+	}
+	// the mock caller has no username attribute
+	_, err = testStub.MockInvoke("t123", "receiveStock", []string{"1", "5", "delivery"})
+	if ccerror.CodeOf(err) != ccerror.Unauthorized {
+		t.Fatalf("receiveStock without a username returned %v", err)
+	}
+}
+
+// userStub gives the MockStub the username attribute and the transaction time it does not provide
+type userStub struct {
+	*shim.MockStub
+	username string
+	now      time.Time
+}
+
+func (s *userStub) ReadCertAttribute(attributeName string) ([]byte, error) {
+	if attributeName != UserAttribute {
+		return nil, nil
+	}
+	return []byte(s.username), nil
+}
+
+func (s *userStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+func (s *userStub) invoke(function string, args ...string) ([]byte, error) {
+	return new(MyChaincode).Invoke(s, function, args)
+}
+
+// TestStockMovementHistory test the movements recorded for an object
+func TestStockMovementHistory(t *testing.T) {
+	testStub := &userStub{MockStub: shim.NewMockStub("mock", new(MyChaincode)), username: "alice", now: time.Date(2017, 7, 14, 10, 0, 0, 0, time.UTC)}
+	testStub.invoke("addObject", `{"id": "1", "name":"Pencils","qty":10,"price":1}`)
+
+	for _, c := range []struct {
+		function string
+		quantity string
+		reason   string
+		object   string
+	}{
+		{"receiveStock", "5", "delivery", `{"id":"1","name":"Pencils","qty":15,"price":1}`},
+		{"issueStock", "15", "sale", `{"id":"1","name":"Pencils","qty":0,"price":1}`},
+		{"adjustStock", "3", "count", `{"id":"1","name":"Pencils","qty":3,"price":1}`},
+	} {
+		testStub.now = testStub.now.Add(time.Hour)
+		bytes, err := testStub.invoke(c.function, "1", c.quantity, c.reason)
+		if err != nil || string(bytes) != c.object {
+			t.Fatalf("%s returned %s %v", c.function, bytes, err)
+		}
+	}
+	testStub.username = "bob"
+	for _, args := range [][]string{{"issueStock", "1", "4", "sale"}, {"adjustStock", "1", "-4", "count"}} {
+		if _, err := testStub.invoke(args[0], args[1:]...); ccerror.CodeOf(err) != ccerror.InvalidState {
+			t.Fatalf("%s below zero returned %v", args[0], err)
+		}
+	}
+	if _, err := testStub.invoke("receiveStock", "2", "1", "delivery"); ccerror.CodeOf(err) != ccerror.NotFound {
+		t.Fatalf("receiveStock of a missing object returned %v", err)
+	}
+
+	var movements []StockMovement
+	bytes, err := testStub.MockQuery("getStockMovements", []string{"1"})
+	if err != nil || json.Unmarshal(bytes, &movements) != nil {
+		t.Fatalf("getStockMovements returned %s %v", bytes, err)
+	}
+	date := time.Date(2017, 7, 14, 10, 0, 0, 0, time.UTC)
+	expected := []StockMovement{
+		{ObjectID: "1", Type: StockReceipt, Delta: 5, Quantity: 15, Reason: "delivery", User: "alice", Date: date.Add(time.Hour)},
+		{ObjectID: "1", Type: StockIssue, Delta: -15, Quantity: 0, Reason: "sale", User: "alice", Date: date.Add(2 * time.Hour)},
+		{ObjectID: "1", Type: StockAdjustment, Delta: 3, Quantity: 3, Reason: "count", User: "alice", Date: date.Add(3 * time.Hour)},
+	}
+	if !reflect.DeepEqual(movements, expected) {
+		t.Fatalf("getStockMovements returned %v, expecting %v", movements, expected)
+	}
+}
+
+// TestInventoryReports test the valuation and low stock queries
+func TestInventoryReports(t *testing.T) {
+	testStub := shim.NewMockStub("mock", new(MyChaincode))
+	testStub.MockInit("t123", "init", nil)
+
+	bytes, err := testStub.MockQuery("getInventoryValuation", []string{})
+	if err != nil || string(bytes) != `{"objects":[],"qty":0,"value":0}` {
+		t.Fatalf("getInventoryValuation returned %s %v", bytes, err)
+	}
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "2", "name":"Pens","qty":10,"price":0.1}`})
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "1", "name":"Pencils","qty":1000,"price":2.5}`})
+	testStub.MockInvoke("t123", "addObject", []string{`{"id": "3", "name":"Erasers","qty":0,"price":1}`})
+
+	bytes, err = testStub.MockQuery("getInventoryValuation", []string{})
+	if err != nil || string(bytes) != `{"objects":[{"id":"1","name":"Pencils","qty":1000,"price":2.5,"value":2500},{"id":"2","name":"Pens","qty":10,"price":0.1,"value":1},{"id":"3","name":"Erasers","qty":0,"price":1,"value":0}],"qty":1010,"value":2501}` {
+		t.Fatalf("getInventoryValuation returned %s %v", bytes, err)
+	}
+	bytes, err = testStub.MockQuery("getLowStockObjects", []string{"10"})
+	if err != nil || string(bytes) != `[{"id":"3","name":"Erasers","qty":0,"price":1}]` {
+		t.Fatalf("getLowStockObjects returned %s %v", bytes, err)
+	}
+	bytes, err = testStub.MockQuery("getLowStockObjects", []string{"0"})
+	if err != nil || string(bytes) != `[]` {
+		t.Fatalf("getLowStockObjects returned %s %v", bytes, err)
+	}
+}
+
+// TestAddObjectValidation test the objects refused by addObject and createObject
+func TestAddObjectValidation(t *testing.T) {
+	testStub := shim.NewMockStub("mock", new(MyChaincode))
+	testStub.MockInit("t123", "init", nil)
+
+	for _, objectBlob := range []string{
+		`{"name":"Pencils","qty":1,"price":1}`,
+		`{"id": "ListOfObjects", "name":"Pencils","qty":1,"price":1}`,
+		`{"id": "1", "qty":1,"price":1}`,
+		`{"id": "1", "name":"Pencils","qty":-1,"price":1}`,
+		`{"id": "1", "name":"Pencils","qty":1,"price":-1}`,
+		`{"id": "1", "name":"Pencils","qty":1,"price":1,"colour":"red"}`,
+		`{"id": "1", "name":"Pencils","qty":"many","price":1}`,
+	} {
+		if _, err := testStub.MockInvoke("t123", "addObject", []string{objectBlob}); ccerror.CodeOf(err) != ccerror.BadArgs {
+			t.Fatalf("addObject of %s returned %v", objectBlob, err)
+		}
+	}
+	if bytes, _ := testStub.MockQuery("getAllObjects", []string{}); string(bytes) != `[]` {
+		t.Fatalf("invalid objects stored : %s", bytes)
+	}
+
+	bytes, err := testStub.MockInvoke("t123", "createObject", []string{`{"id": "1", "name":"Pencils","qty":1,"price":1}`})
+	if err != nil || string(bytes) != `{"id":"1","name":"Pencils","qty":1,"price":1}` {
+		t.Fatalf("createObject returned %s %v", bytes, err)
+	}
+	_, err = testStub.MockInvoke("t123", "createObject", []string{`{"id": "1", "name":"Pens","qty":2,"price":2}`})
+	if ccerror.CodeOf(err) != ccerror.Conflict {
+		t.Fatalf("createObject of an existing id returned %v", err)
+	}
+	bytes, err = testStub.MockInvoke("t123", "addObject", []string{`{"id": "1", "name":"Pens","qty":2,"price":2}`})
+	if err != nil || string(bytes) != `{"id":"1","name":"Pens","qty":2,"price":2}` {
+		t.Fatalf("addObject returned %s %v", bytes, err)
+	}
+	_, err = testStub.MockInvoke("t123", "updateObject", []string{`{"id": "1", "qty":-2}`})
+	if ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("updateObject to a negative quantity returned %v", err)
+	}
+}
@@ -17,10 +17,11 @@ limitations under the License.
 package main
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+	"github.com/ruchika05/learn-chaincode/router"
 )
 
 // SimpleChaincode example simple Chaincode implementation
@@ -37,43 +38,40 @@ func main() {
 // Init resets all the things
 func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	if len(args) != 1 {
-		return nil, errors.New("Incorrect number of arguments. Expecting 1")
+		return nil, ccerror.New(ccerror.BadArgs, "Incorrect number of arguments. Expecting 1")
 	}
 
 	err := stub.PutState("hello_world", []byte(args[0]))
 	if err != nil {
-		return nil, err
+		return nil, ccerror.Wrap(err)
 	}
 
 	return nil, nil
 }
 
+// Returns the functions of the chaincode
+func (t *SimpleChaincode) routes() *router.Router {
+	return router.New().
+		Invoke("init", "Resets the hello_world key to a value",
+			router.Strings("value"), func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+				return t.Init(stub, "init", args)
+			}).
+		Invoke("write", "Writes a value under a key",
+			router.Strings("key", "value"), t.write).
+		Query("read", "Returns the value stored under a key",
+			router.Strings("key"), t.read)
+}
+
 // Invoke isur entry point to invoke a chaincode function
 func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("invoke is running " + function)
-
-	// Handle different functions
-	if function == "init" {
-		return t.Init(stub, "init", args)
-	} else if function == "write" {
-		return t.write(stub, args)
-	}
-	fmt.Println("invoke did not find func: " + function)
-
-	return nil, errors.New("Received unknown function invocation: " + function)
+	return t.routes().Handle(stub, router.Invoke, function, args)
 }
 
 // Query is our entry point for queries
 func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("query is running " + function)
-
-	// Handle different functions
-	if function == "read" { //read a variable
-		return t.read(stub, args)
-	}
-	fmt.Println("query did not find func: " + function)
-
-	return nil, errors.New("Received unknown function query: " + function)
+	return t.routes().Handle(stub, router.Query, function, args)
 }
 
 // write - invoke function to write key/value pair
@@ -82,33 +80,27 @@ func (t *SimpleChaincode) write(stub shim.ChaincodeStubInterface, args []string)
 	var err error
 	fmt.Println("running write()")
 
-	if len(args) != 2 {
-		return nil, errors.New("Incorrect number of arguments. Expecting 2. name of the key and value to set")
-	}
-
 	key = args[0] //rename for funsies
 	value = args[1]
 	err = stub.PutState(key, []byte(value)) //write the variable into the chaincode state
 	if err != nil {
-		return nil, err
+		return nil, ccerror.Wrap(err)
 	}
 	return nil, nil
 }
 
 // read - query function to read key/value pair
 func (t *SimpleChaincode) read(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	var key, jsonResp string
+	var key string
 	var err error
 
-	if len(args) != 1 {
-		return nil, errors.New("Incorrect number of arguments. Expecting name of the key to query")
-	}
-
 	key = args[0]
 	valAsbytes, err := stub.GetState(key)
 	if err != nil {
-		jsonResp = "{\"Error\":\"Failed to get state for " + key + "\"}"
-		return nil, errors.New(jsonResp)
+		return nil, ccerror.New(ccerror.Internal, "Failed to get state for "+key)
+	}
+	if valAsbytes == nil {
+		return nil, ccerror.New(ccerror.NotFound, "No value for "+key)
 	}
 
 	return valAsbytes, nil
@@ -0,0 +1,164 @@
+// Package router dispatches the Invoke and Query calls of a chaincode to the functions
+// registered with a Router. The arguments of a call are checked against the ones the
+// function declares before it is called, so that every chaincode reports a wrong call
+// the same way. A built-in help query lists the functions registered.
+package router
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+)
+
+// Kind tells whether a function is called through Invoke or Query
+type Kind string
+
+// Kinds of functions
+const (
+	Invoke Kind = "invoke"
+	Query  Kind = "query"
+)
+
+// HelpFunction is the name of the built-in query listing the registered functions
+const HelpFunction = "help"
+
+// ArgType is the form an argument must have
+type ArgType string
+
+// Argument types
+const (
+	String ArgType = "string" // any string, possibly empty
+	Number ArgType = "number" // a decimal number
+	Object ArgType = "object" // a JSON object
+	Array  ArgType = "array"  // a JSON array
+)
+
+// Arg describes one argument of a function
+type Arg struct {
+	Name string  `json:"name"`
+	Type ArgType `json:"type"`
+}
+
+// Handler is called with the arguments of a call once they have been checked
+type Handler func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error)
+
+// Function is a function registered with a Router
+type Function struct {
+	Name        string  `json:"name"`
+	Kind        Kind    `json:"kind"`
+	Args        []Arg   `json:"args"` // arguments expected, in order
+	Description string  `json:"description"`
+	Handler     Handler `json:"-"`
+}
+
+// Router holds the functions of a chaincode
+type Router struct {
+	functions map[Kind]map[string]Function
+	ordered   []Function // registered functions, in the order they were registered
+}
+
+// New returns a Router without any function
+func New() *Router {
+	return &Router{functions: map[Kind]map[string]Function{Invoke: {}, Query: {}}}
+}
+
+// Register adds f to r and returns r, so that registrations can be chained.
+// It panics when a function of the same kind and name is already registered.
+func (r *Router) Register(f Function) *Router {
+	if _, ok := r.functions[f.Kind][f.Name]; ok {
+		panic("router: " + string(f.Kind) + " function " + f.Name + " registered twice")
+	}
+	if f.Args == nil {
+		f.Args = []Arg{}
+	}
+	r.functions[f.Kind][f.Name] = f
+	r.ordered = append(r.ordered, f)
+	return r
+}
+
+// Invoke registers the invoke function name taking args
+func (r *Router) Invoke(name string, description string, args []Arg, handler Handler) *Router {
+	return r.Register(Function{Name: name, Kind: Invoke, Args: args, Description: description, Handler: handler})
+}
+
+// Query registers the query function name taking args
+func (r *Router) Query(name string, description string, args []Arg, handler Handler) *Router {
+	return r.Register(Function{Name: name, Kind: Query, Args: args, Description: description, Handler: handler})
+}
+
+// Functions returns the registered functions, in the order they were registered
+func (r *Router) Functions() []Function {
+	return r.ordered
+}
+
+// Handle calls the function of kind registered under function with args.
+// Every error returned is a *ccerror.Error: bad-args for an unknown function or arguments
+// that do not match the ones it declares, and the error of the function otherwise.
+func (r *Router) Handle(stub shim.ChaincodeStubInterface, kind Kind, function string, args []string) ([]byte, error) {
+	f, ok := r.functions[kind][function]
+	if !ok {
+		if kind == Query && function == HelpFunction {
+			return json.Marshal(r.ordered)
+		}
+		if kind == Invoke {
+			return nil, ccerror.New(ccerror.BadArgs, "Received unknown function invocation: "+function)
+		}
+		return nil, ccerror.New(ccerror.BadArgs, "Received unknown function query: "+function)
+	}
+	if err := f.checkArgs(args); err != nil {
+		return nil, err
+	}
+	payload, err := f.Handler(stub, args)
+	return payload, ccerror.Wrap(err)
+}
+
+// Returns the usage of f, function(arg1 type1, arg2 type2)
+func (f Function) usage() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = arg.Name + " " + string(arg.Type)
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Checks that args match the arguments f declares
+func (f Function) checkArgs(args []string) error {
+	if len(args) != len(f.Args) {
+		return ccerror.Errorf(ccerror.BadArgs, "Incorrect number of arguments to call %s: expecting %d, got %d. Usage: %s",
+			f.Name, len(f.Args), len(args), f.usage())
+	}
+	for i, arg := range f.Args {
+		if !arg.Type.matches(args[i]) {
+			return ccerror.Errorf(ccerror.BadArgs, "Argument %s of %s is not of type %s. Usage: %s", arg.Name, f.Name, arg.Type, f.usage())
+		}
+	}
+	return nil
+}
+
+// Tells whether value has the form t
+func (t ArgType) matches(value string) bool {
+	switch t {
+	case Number:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case Object:
+		var object map[string]interface{}
+		return json.Unmarshal([]byte(value), &object) == nil && object != nil
+	case Array:
+		var array []interface{}
+		return json.Unmarshal([]byte(value), &array) == nil && array != nil
+	}
+	return true
+}
+
+// Strings returns string arguments named names
+func Strings(names ...string) []Arg {
+	args := make([]Arg, len(names))
+	for i, name := range names {
+		args[i] = Arg{Name: name, Type: String}
+	}
+	return args
+}
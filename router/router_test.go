@@ -0,0 +1,39 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ruchika05/learn-chaincode/ccerror"
+)
+
+// TestRouter test the dispatch and the argument checks
+func TestRouter(t *testing.T) {
+	echo := func(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return []byte(args[0]), nil
+	}
+	r := New().
+		Invoke("write", "Writes an object", []Arg{{Name: "object", Type: Object}}, echo).
+		Query("read", "Reads a key", Strings("key"), echo)
+
+	payload, err := r.Handle(nil, Query, "read", []string{"a"})
+	if err != nil || string(payload) != "a" {
+		t.Fatalf("Unexpected result %s %v", payload, err)
+	}
+	if _, err = r.Handle(nil, Invoke, "read", []string{"a"}); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("A query should not be invoked : %v", err)
+	}
+	if _, err = r.Handle(nil, Query, "read", nil); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("Missing arguments should be refused : %v", err)
+	}
+	if _, err = r.Handle(nil, Invoke, "write", []string{"[1]"}); ccerror.CodeOf(err) != ccerror.BadArgs {
+		t.Fatalf("An array should not be taken for an object : %v", err)
+	}
+
+	var functions []Function
+	payload, err = r.Handle(nil, Query, HelpFunction, nil)
+	if err != nil || json.Unmarshal(payload, &functions) != nil || len(functions) != 2 {
+		t.Fatalf("Unexpected help %s %v", payload, err)
+	}
+}